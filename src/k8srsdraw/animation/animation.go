@@ -1,13 +1,19 @@
 package animation
 
 import (
+	"image"
 	"image/color"
 	"k8srsdraw/drawapi"
+	"math"
 	"sync"
 	"time"
 )
 
+// A shape is shown by registering it in the scene of its drawer at its Z,
+// and hidden by removing it from there. Changing a shown shape asks the
+// scene for a new frame.
 type DrawerShape interface {
+	drawapi.Painter
 	Hide()
 	IsHide() bool
 	GetDrawer() *drawapi.Drawer
@@ -15,78 +21,78 @@ type DrawerShape interface {
 	GetStop() chan int
 	GetColor() color.Color
 	Draw()
-	DrawByColor(c color.Color)
+	// SetBlinkOff turns a shown shape off or on again, as flickering does.
+	SetBlinkOff(off bool)
 }
 
 type MoveListener interface {
 	MoveStatue(s int)
 }
 
+// FlickerPeriod is how long a flickering shape stays on, then off.
+var FlickerPeriod = 300 * time.Millisecond
+
+// MoveTo slides shape from from to to over duration, easing in and out.
 func MoveTo(shape DrawerShape, from, to drawapi.DrawPoint, duration time.Duration, ml MoveListener) {
-	go func() {
-		dx := to.X - from.X
-		dy := to.Y - from.Y
-		steps := int(int64(duration.Nanoseconds()) / (100 * int64(time.Millisecond)))
-	ExitFor:
-		for i := 1; i <= steps; i++ {
-			x := from.X + i*dx/steps
-			y := from.Y + i*dy/steps
-			if ((dx >= 0 && x >= to.X) || (dx <= 0 && x <= to.X)) &&
-				((dy >= 0 && y >= to.Y) || (dy <= 0 && y <= to.Y)) {
-				break
-			}
-			shape.DrawByColor(shape.GetDrawer().GetBackGround())
-			shape.SetStartPoint(drawapi.DrawPoint{x, y})
+	MoveToWithEasing(shape, from, to, duration, EaseInOut, ml)
+}
+func MoveToWithEasing(shape DrawerShape, from, to drawapi.DrawPoint, duration time.Duration, ease Easing,
+	ml MoveListener) {
+	tw := &Tween{
+		Duration: duration,
+		Ease:     ease,
+		Stop:     shape.GetStop(),
+		Update: func(p float64) bool {
 			if shape.IsHide() {
-				break ExitFor
+				return false
 			}
 			if ml != nil {
 				ml.MoveStatue(0)
 			}
-			shape.Draw()
+			shape.SetStartPoint(lerp(from, to, p))
 			if ml != nil {
 				ml.MoveStatue(1)
 			}
-			select {
-			case <-time.After(100 * time.Millisecond):
-			case <-shape.GetStop():
-				break ExitFor
-			}
-		}
-		if ml != nil {
+			return true
+		},
+	}
+	if ml != nil {
+		tw.Done = func() {
 			ml.MoveStatue(2)
 		}
-	}()
+	}
+	tw.Start(shape.GetDrawer())
 }
+
+// StartFlicker turns shape off and on every FlickerPeriod for at least t,
+// it always ends on.
 func StartFlicker(shape DrawerShape, t time.Duration) {
-	go func() {
-		isShow := true
-		count := 0
-	ExitFor:
-		for {
-			select {
-			case <-time.After(300 * time.Millisecond):
-				if shape.IsHide() {
-					break ExitFor
-				}
-				if isShow {
-					shape.DrawByColor(shape.GetDrawer().GetBackGround())
-					isShow = false
-				} else {
-					shape.Draw()
-					isShow = true
-				}
-				count++
-				if time.Duration(count*300)*time.Millisecond >= t && isShow {
-					break ExitFor
-				}
-			case <-shape.GetStop():
-				shape.Draw()
-				break ExitFor
+	periods := int((t + FlickerPeriod - 1) / FlickerPeriod)
+	if periods%2 == 1 {
+		periods++
+	}
+	duration := time.Duration(periods) * FlickerPeriod
+	(&Tween{
+		Duration: duration,
+		Stop:     shape.GetStop(),
+		Update: func(p float64) bool {
+			if shape.IsHide() {
+				return false
 			}
-		}
+			shape.SetBlinkOff(int(p*float64(duration)/float64(FlickerPeriod))%2 == 1)
+			return true
+		},
+		Done: func() {
+			shape.SetBlinkOff(false)
+		},
+	}).Start(shape.GetDrawer())
+}
 
-	}()
+func lerp(from, to drawapi.DrawPoint, p float64) drawapi.DrawPoint {
+	return drawapi.DrawPoint{
+		X: from.X + int(math.Round(float64(to.X-from.X)*p)),
+		Y: from.Y + int(math.Round(float64(to.Y-from.Y)*p)),
+	}
 }
 
 type Animation interface {
@@ -102,9 +108,11 @@ type Rect struct {
 	Height        int
 	Color         color.Color
 	IsFill        bool
+	Z             int
 	stopAnimation chan int
 	mutux         sync.Mutex
 	isHided       bool
+	blinkOff      bool
 }
 
 func NewRect(d *drawapi.Drawer, startPoint drawapi.DrawPoint, w, h int, c color.Color, isFill bool) *Rect {
@@ -124,14 +132,21 @@ func (r *Rect) GetColor() color.Color {
 	return r.Color
 }
 func (r *Rect) Draw() {
-	r.DrawByColor(r.Color)
+	r.mutux.Lock()
 	r.isHided = false
+	r.blinkOff = false
+	r.mutux.Unlock()
+	r.Drawer.Scene().Add(r, r.Z)
 }
 func (r *Rect) Hide() {
-	r.DrawByColor(r.GetDrawer().GetBackGround())
+	r.mutux.Lock()
 	r.isHided = true
+	r.mutux.Unlock()
+	r.Drawer.Scene().Remove(r)
 }
 func (r *Rect) IsHide() bool {
+	r.mutux.Lock()
+	defer r.mutux.Unlock()
 	return r.isHided
 }
 func (r *Rect) GetStop() chan int {
@@ -141,15 +156,27 @@ func (r *Rect) GetDrawer() *drawapi.Drawer {
 	return r.Drawer
 }
 func (r *Rect) SetStartPoint(point drawapi.DrawPoint) {
+	r.mutux.Lock()
 	r.StartPoint = point
+	r.mutux.Unlock()
+	r.Drawer.Scene().Invalidate()
+}
+func (r *Rect) SetBlinkOff(off bool) {
+	r.mutux.Lock()
+	r.blinkOff = off
+	r.mutux.Unlock()
+	r.Drawer.Scene().Invalidate()
 }
-func (r *Rect) DrawByColor(c color.Color) {
+func (r *Rect) Paint(cv *drawapi.Canvas) {
 	r.mutux.Lock()
 	defer r.mutux.Unlock()
+	if r.blinkOff {
+		return
+	}
 	if r.IsFill {
-		r.Drawer.FillRect(r.StartPoint, r.Width, r.Height, c)
+		cv.FillRect(r.StartPoint, r.Width, r.Height, r.Color)
 	} else {
-		r.Drawer.DrawRect(r.StartPoint, r.Width, r.Height, c)
+		cv.DrawRect(r.StartPoint, r.Width, r.Height, r.Color)
 	}
 }
 
@@ -169,9 +196,11 @@ type Line struct {
 	StartPoint    drawapi.DrawPoint
 	EndPoint      drawapi.DrawPoint
 	Color         color.Color
+	Z             int
 	mutux         sync.Mutex
 	stopAnimation chan int
 	isHide        bool
+	blinkOff      bool
 }
 
 func NewLine(d *drawapi.Drawer, startPoint, endPoint drawapi.DrawPoint, c color.Color) *Line {
@@ -190,10 +219,12 @@ func (l *Line) GetDrawer() *drawapi.Drawer {
 	return l.Drawer
 }
 func (l *Line) SetStartPoint(point drawapi.DrawPoint) {
+	l.mutux.Lock()
 	l.EndPoint.X += point.X - l.StartPoint.X
 	l.EndPoint.Y += point.Y - l.StartPoint.Y
 	l.StartPoint = point
-
+	l.mutux.Unlock()
+	l.Drawer.Scene().Invalidate()
 }
 func (l *Line) GetStop() chan int {
 	return l.stopAnimation
@@ -202,20 +233,57 @@ func (l *Line) GetColor() color.Color {
 	return l.Color
 }
 func (l *Line) Draw() {
-	l.DrawByColor(l.Color)
+	l.mutux.Lock()
 	l.isHide = false
+	l.blinkOff = false
+	l.mutux.Unlock()
+	l.Drawer.Scene().Add(l, l.Z)
 }
 func (l *Line) Hide() {
-	l.DrawByColor(l.GetDrawer().GetBackGround())
+	l.mutux.Lock()
 	l.isHide = true
+	l.mutux.Unlock()
+	l.Drawer.Scene().Remove(l)
 }
 func (l *Line) IsHide() bool {
+	l.mutux.Lock()
+	defer l.mutux.Unlock()
 	return l.isHide
 }
-func (l *Line) DrawByColor(c color.Color) {
+func (l *Line) SetBlinkOff(off bool) {
+	l.mutux.Lock()
+	l.blinkOff = off
+	l.mutux.Unlock()
+	l.Drawer.Scene().Invalidate()
+}
+func (l *Line) Paint(cv *drawapi.Canvas) {
 	l.mutux.Lock()
 	defer l.mutux.Unlock()
-	l.Drawer.DrawLine(l.StartPoint, l.EndPoint, c)
+	if l.blinkOff {
+		return
+	}
+	cv.DrawLine(l.StartPoint, l.EndPoint, l.Color)
+}
+
+// GrowTo shows the line and stretches its end from its start to to over
+// duration, it returns once the line is complete.
+func (l *Line) GrowTo(to drawapi.DrawPoint, duration time.Duration) {
+	l.mutux.Lock()
+	from := l.StartPoint
+	l.EndPoint = from
+	l.mutux.Unlock()
+	l.Draw()
+	<-(&Tween{
+		Duration: duration,
+		Ease:     EaseOut,
+		Update: func(p float64) bool {
+			l.mutux.Lock()
+			l.EndPoint = lerp(from, to, p)
+			l.mutux.Unlock()
+			l.Drawer.Scene().Invalidate()
+			return true
+		},
+	}).Start(l.Drawer)
 }
 
 func (l *Line) StopAnimation() {
@@ -230,6 +298,8 @@ func (l *Line) MoveTo(from, to drawapi.DrawPoint, duration time.Duration, ml Mov
 	MoveTo(l, from, to, duration, ml)
 }
 
+// TextWidgt is text in the box at StartPoint of Width x Height, laid out
+// following Layout.
 type TextWidgt struct {
 	Drawer        *drawapi.Drawer
 	StartPoint    drawapi.DrawPoint
@@ -237,10 +307,13 @@ type TextWidgt struct {
 	Height        int
 	Text          string
 	Color         color.Color
+	Layout        drawapi.TextLayout
+	Z             int
 	mutux         sync.Mutex
 	FontSize      float64
 	stopAnimation chan int
 	isHide        bool
+	blinkOff      bool
 }
 
 func NewTextWidgt(d *drawapi.Drawer, startPoint drawapi.DrawPoint, width, height int, text string,
@@ -265,7 +338,10 @@ func (t *TextWidgt) GetDrawer() *drawapi.Drawer {
 	return t.Drawer
 }
 func (t *TextWidgt) SetStartPoint(point drawapi.DrawPoint) {
+	t.mutux.Lock()
 	t.StartPoint = point
+	t.mutux.Unlock()
+	t.Drawer.Scene().Invalidate()
 }
 func (t *TextWidgt) GetStop() chan int {
 	return t.stopAnimation
@@ -274,22 +350,37 @@ func (t *TextWidgt) GetColor() color.Color {
 	return t.Color
 }
 func (t *TextWidgt) Draw() {
-	t.DrawByColor(t.Color)
+	t.mutux.Lock()
 	t.isHide = false
+	t.blinkOff = false
+	t.mutux.Unlock()
+	t.Drawer.Scene().Add(t, t.Z)
 }
 func (t *TextWidgt) Hide() {
-	//t.DrawByColor(t.GetDrawer().GetBackGround())
-	t.GetDrawer().FillRect(t.StartPoint, t.Width, t.Height, t.GetDrawer().GetBackGround())
+	t.mutux.Lock()
 	t.isHide = true
+	t.mutux.Unlock()
+	t.Drawer.Scene().Remove(t)
 }
 func (t *TextWidgt) IsHide() bool {
+	t.mutux.Lock()
+	defer t.mutux.Unlock()
 	return t.isHide
 }
-func (t *TextWidgt) DrawByColor(c color.Color) {
+func (t *TextWidgt) SetBlinkOff(off bool) {
+	t.mutux.Lock()
+	t.blinkOff = off
+	t.mutux.Unlock()
+	t.Drawer.Scene().Invalidate()
+}
+func (t *TextWidgt) Paint(cv *drawapi.Canvas) {
 	t.mutux.Lock()
 	defer t.mutux.Unlock()
-	t.Drawer.DrawText(t.StartPoint,
-		t.Drawer.GetStrByWidth(t.Text, t.FontSize, t.Width), t.FontSize, t.Color)
+	if t.blinkOff {
+		return
+	}
+	box := image.Rect(t.StartPoint.X, t.StartPoint.Y, t.StartPoint.X+t.Width, t.StartPoint.Y+t.Height)
+	cv.DrawTextBox(box, t.Text, t.FontSize, t.Layout, t.Color)
 }
 
 func (t *TextWidgt) StopAnimation() {
@@ -310,9 +401,11 @@ type Circle struct {
 	Radius        int
 	IsFill        bool
 	Color         color.Color
+	Z             int
 	mutux         sync.Mutex
 	stopAnimation chan int
 	isHide        bool
+	blinkOff      bool
 }
 
 func NewCircle(d *drawapi.Drawer, startPoint drawapi.DrawPoint, radius int, isFill bool,
@@ -332,7 +425,10 @@ func (c *Circle) GetDrawer() *drawapi.Drawer {
 	return c.Drawer
 }
 func (c *Circle) SetStartPoint(point drawapi.DrawPoint) {
+	c.mutux.Lock()
 	c.StartPoint = point
+	c.mutux.Unlock()
+	c.Drawer.Scene().Invalidate()
 }
 func (c *Circle) GetStop() chan int {
 	return c.stopAnimation
@@ -341,20 +437,36 @@ func (c *Circle) GetColor() color.Color {
 	return c.Color
 }
 func (c *Circle) Draw() {
-	c.DrawByColor(c.Color)
+	c.mutux.Lock()
 	c.isHide = false
+	c.blinkOff = false
+	c.mutux.Unlock()
+	c.Drawer.Scene().Add(c, c.Z)
 }
 func (c *Circle) Hide() {
-	c.DrawByColor(c.GetDrawer().GetBackGround())
+	c.mutux.Lock()
 	c.isHide = true
+	c.mutux.Unlock()
+	c.Drawer.Scene().Remove(c)
 }
 func (c *Circle) IsHide() bool {
+	c.mutux.Lock()
+	defer c.mutux.Unlock()
 	return c.isHide
 }
-func (c *Circle) DrawByColor(color color.Color) {
+func (c *Circle) SetBlinkOff(off bool) {
+	c.mutux.Lock()
+	c.blinkOff = off
+	c.mutux.Unlock()
+	c.Drawer.Scene().Invalidate()
+}
+func (c *Circle) Paint(cv *drawapi.Canvas) {
 	c.mutux.Lock()
 	defer c.mutux.Unlock()
-	c.Drawer.DrawCircle(c.StartPoint, c.Radius, c.IsFill, color)
+	if c.blinkOff {
+		return
+	}
+	cv.DrawCircle(c.StartPoint, c.Radius, c.IsFill, c.Color)
 }
 
 func (c *Circle) StopAnimation() {
@@ -0,0 +1,178 @@
+package animation
+
+import (
+	"image/color"
+	"k8srsdraw/drawapi"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	// TrailLength is the share of the route the trail of a token covers.
+	TrailLength = 0.35
+	TokenRadius = 6.0
+	// RouteAlpha is the opacity of the route ahead of the token.
+	RouteAlpha = 0.35
+)
+
+// CurvedArrow is a pod on its way between two nodes: a curved arrow from
+// From to To, and a token traveling along it with a fading trail.
+type CurvedArrow struct {
+	Drawer     *drawapi.Drawer
+	From       drawapi.PointF
+	To         drawapi.PointF
+	Color      color.Color
+	TokenColor color.Color
+	Width      float64
+	Z          int
+	mutux      sync.Mutex
+	route      []drawapi.PointF
+	progress   float64
+	opacity    float64
+	isHide     bool
+}
+
+// NewCurvedArrow returns an arrow bowed by bend times its length, towards
+// the top of the window so that arrows going either way stay on screen.
+func NewCurvedArrow(d *drawapi.Drawer, from, to drawapi.PointF, bend float64, c, tokenColor color.Color) *CurvedArrow {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	l := math.Hypot(dx, dy)
+	// both control points are moved by h, which moves the middle of the
+	// curve by 3/4 h
+	h := bend * l * 4 / 3
+	ox, oy := 0.0, 0.0
+	if l > 0 {
+		ox, oy = dy/l*h, -dx/l*h
+		if oy > 0 {
+			ox, oy = -ox, -oy
+		}
+	}
+	p1 := drawapi.PointF{X: from.X + dx/3 + ox, Y: from.Y + dy/3 + oy}
+	p2 := drawapi.PointF{X: from.X + dx*2/3 + ox, Y: from.Y + dy*2/3 + oy}
+	n := int(math.Max(16, math.Min(120, l/4)))
+	return &CurvedArrow{
+		Drawer:     d,
+		From:       from,
+		To:         to,
+		Color:      c,
+		TokenColor: tokenColor,
+		Width:      2,
+		route:      drawapi.Bezier(from, p1, p2, to, n),
+		opacity:    1,
+		isHide:     true,
+	}
+}
+func (a *CurvedArrow) GetDrawer() *drawapi.Drawer {
+	return a.Drawer
+}
+func (a *CurvedArrow) Draw() {
+	a.mutux.Lock()
+	a.isHide = false
+	a.mutux.Unlock()
+	a.Drawer.Scene().Add(a, a.Z)
+}
+func (a *CurvedArrow) Hide() {
+	a.mutux.Lock()
+	a.isHide = true
+	a.mutux.Unlock()
+	a.Drawer.Scene().Remove(a)
+}
+func (a *CurvedArrow) IsHide() bool {
+	a.mutux.Lock()
+	defer a.mutux.Unlock()
+	return a.isHide
+}
+
+// SetProgress puts the token at p along the route, from 0 to 1.
+func (a *CurvedArrow) SetProgress(p float64) {
+	a.mutux.Lock()
+	a.progress = math.Max(0, math.Min(1, p))
+	a.mutux.Unlock()
+	a.Drawer.Scene().Invalidate()
+}
+func (a *CurvedArrow) SetOpacity(o float64) {
+	a.mutux.Lock()
+	a.opacity = math.Max(0, math.Min(1, o))
+	a.mutux.Unlock()
+	a.Drawer.Scene().Invalidate()
+}
+
+// Fly shows the arrow and moves its token from From to To over duration,
+// it returns once the token landed.
+func (a *CurvedArrow) Fly(duration time.Duration) {
+	a.SetProgress(0)
+	a.Draw()
+	<-(&Tween{
+		Duration: duration,
+		Ease:     EaseInOut,
+		Update: func(p float64) bool {
+			a.SetProgress(p)
+			return !a.IsHide()
+		},
+	}).Start(a.Drawer)
+}
+
+// FadeOut fades the arrow away over duration then hides it, it returns at
+// once.
+func (a *CurvedArrow) FadeOut(duration time.Duration) {
+	(&Tween{
+		Duration: duration,
+		Ease:     EaseOut,
+		Update: func(p float64) bool {
+			a.SetOpacity(1 - p)
+			return !a.IsHide()
+		},
+		Done: a.Hide,
+	}).Start(a.Drawer)
+}
+
+// at returns the point at p along the route, and the index of the route
+// point before it.
+func (a *CurvedArrow) at(p float64) (drawapi.PointF, int) {
+	f := p * float64(len(a.route)-1)
+	i := int(f)
+	if i >= len(a.route)-1 {
+		return a.route[len(a.route)-1], len(a.route) - 2
+	}
+	t := f - float64(i)
+	return drawapi.PointF{
+		X: a.route[i].X + (a.route[i+1].X-a.route[i].X)*t,
+		Y: a.route[i].Y + (a.route[i+1].Y-a.route[i].Y)*t,
+	}, i
+}
+
+func (a *CurvedArrow) Paint(cv *drawapi.Canvas) {
+	a.mutux.Lock()
+	defer a.mutux.Unlock()
+	if len(a.route) < 2 || a.opacity == 0 {
+		return
+	}
+	last := len(a.route) - 1
+	cv.StrokePolyline(a.route, a.Width*0.75, withAlpha(a.Color, RouteAlpha*a.opacity))
+	cv.FillPolygon(drawapi.ArrowHead(a.route[last-1], a.route[last], 4*a.Width+4), withAlpha(a.Color, a.opacity))
+
+	token, i := a.at(a.progress)
+	// the trail gets more opaque towards the token
+	start := int(float64(i) - TrailLength*float64(last))
+	if start < 0 {
+		start = 0
+	}
+	for j := start; j <= i; j++ {
+		to := a.route[j+1]
+		if j == i {
+			to = token
+		}
+		alpha := float64(j-start+1) / float64(i-start+1)
+		cv.StrokePolyline([]drawapi.PointF{a.route[j], to}, a.Width+1, withAlpha(a.Color, alpha*a.opacity))
+	}
+	cv.FillCircle(token, TokenRadius, withAlpha(a.TokenColor, a.opacity))
+	cv.StrokeCircle(token, TokenRadius, 1.5, withAlpha(a.Color, a.opacity))
+}
+
+// withAlpha returns c with its opacity multiplied by alpha.
+func withAlpha(c color.Color, alpha float64) color.Color {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	n.A = uint8(float64(n.A) * alpha)
+	return n
+}
@@ -0,0 +1,102 @@
+package animation
+
+import (
+	"image"
+	"image/color"
+	"k8srsdraw/drawapi"
+	"strings"
+	"sync"
+)
+
+var (
+	// TooltipPadding is the space between the border of a tooltip and its
+	// text, TooltipOffset the one between the pointer and the tooltip.
+	TooltipPadding = 6
+	TooltipOffset  = image.Point{12, 16}
+	TooltipRadius  = 4.0
+)
+
+// Tooltip is a panel of lines of text floating next to the pointer.
+type Tooltip struct {
+	Drawer     *drawapi.Drawer
+	Lines      []string
+	FontSize   float64
+	MaxWidth   int
+	Color      color.Color
+	Background color.Color
+	Border     color.Color
+	Z          int
+	mutux      sync.Mutex
+	box        image.Rectangle
+	isHide     bool
+}
+
+func NewTooltip(d *drawapi.Drawer, fontSize float64, maxWidth int, c, background, border color.Color) *Tooltip {
+	return &Tooltip{
+		Drawer:     d,
+		FontSize:   fontSize,
+		MaxWidth:   maxWidth,
+		Color:      c,
+		Background: background,
+		Border:     border,
+		isHide:     true,
+	}
+}
+func (t *Tooltip) GetDrawer() *drawapi.Drawer {
+	return t.Drawer
+}
+
+// ShowAt shows lines next to the pointer at p, on the side of it which
+// keeps the tooltip within bounds.
+func (t *Tooltip) ShowAt(p image.Point, bounds image.Rectangle, lines []string) {
+	layout := drawapi.TextLayout{Wrap: true}
+	laid := t.Drawer.GetFonts().Layout(strings.Join(lines, "\n"), t.FontSize,
+		image.Rect(0, 0, t.MaxWidth, 1<<20), layout)
+	w := 0
+	for _, l := range laid {
+		w = drawapi.Max(w, l.Width)
+	}
+	size := image.Point{w + 2*TooltipPadding, len(laid)*layout.LineHeight(t.FontSize) + 2*TooltipPadding}
+
+	// the lines which do not fit in bounds end with an ellipsis
+	if size.Y > bounds.Dy() {
+		size.Y = bounds.Dy()
+	}
+
+	at := p.Add(TooltipOffset)
+	if at.X+size.X > bounds.Max.X {
+		at.X = p.X - TooltipOffset.X - size.X
+	}
+	if at.Y+size.Y > bounds.Max.Y {
+		at.Y = p.Y - TooltipOffset.Y/2 - size.Y
+	}
+	at.X = drawapi.Max(at.X, bounds.Min.X)
+	at.Y = drawapi.Max(at.Y, bounds.Min.Y)
+
+	t.mutux.Lock()
+	t.Lines = lines
+	t.box = image.Rectangle{at, at.Add(size)}
+	t.isHide = false
+	t.mutux.Unlock()
+	t.Drawer.Scene().Add(t, t.Z)
+}
+func (t *Tooltip) Hide() {
+	t.mutux.Lock()
+	t.isHide = true
+	t.mutux.Unlock()
+	t.Drawer.Scene().Remove(t)
+}
+func (t *Tooltip) IsHide() bool {
+	t.mutux.Lock()
+	defer t.mutux.Unlock()
+	return t.isHide
+}
+func (t *Tooltip) Paint(cv *drawapi.Canvas) {
+	t.mutux.Lock()
+	defer t.mutux.Unlock()
+	sp := drawapi.DrawPoint{X: t.box.Min.X, Y: t.box.Min.Y}
+	cv.FillRoundRect(sp, t.box.Dx(), t.box.Dy(), TooltipRadius, t.Background)
+	cv.StrokeRoundRect(sp, t.box.Dx(), t.box.Dy(), TooltipRadius, 1, t.Border)
+	cv.DrawTextBox(t.box.Inset(TooltipPadding), strings.Join(t.Lines, "\n"), t.FontSize,
+		drawapi.TextLayout{Wrap: true}, t.Color)
+}
@@ -0,0 +1,80 @@
+package animation
+
+import (
+	"k8srsdraw/drawapi"
+	"math"
+	"time"
+)
+
+// Easing maps the elapsed share of a tween, from 0 to 1, to its progress.
+type Easing func(t float64) float64
+
+func Linear(t float64) float64 {
+	return t
+}
+func EaseIn(t float64) float64 {
+	return t * t * t
+}
+func EaseOut(t float64) float64 {
+	return 1 - math.Pow(1-t, 3)
+}
+func EaseInOut(t float64) float64 {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	return 1 - math.Pow(-2*t+2, 3)/2
+}
+
+// Tween is an animator calling Update once per frame with the eased
+// progress, reaching exactly 1 on its last frame.
+type Tween struct {
+	Duration time.Duration
+	// Ease defaults to Linear.
+	Ease Easing
+	// Update returns false to end the tween early.
+	Update func(p float64) bool
+	// Done, if set, is called after the last frame, early end included.
+	Done func()
+	// Stop, if set, ends the tween early once it can be received from.
+	Stop  <-chan int
+	start time.Time
+	done  chan struct{}
+}
+
+// Start hands the tween to the frame loop of d, the returned channel is
+// closed after its last frame.
+func (tw *Tween) Start(d *drawapi.Drawer) <-chan struct{} {
+	tw.done = make(chan struct{})
+	d.Animate(tw)
+	return tw.done
+}
+func (tw *Tween) Step(now time.Time) bool {
+	select {
+	case <-tw.Stop:
+		tw.finish()
+		return false
+	default:
+	}
+	if tw.start.IsZero() {
+		tw.start = now
+	}
+	t := 1.0
+	if tw.Duration > 0 {
+		t = math.Min(float64(now.Sub(tw.start))/float64(tw.Duration), 1)
+	}
+	ease := tw.Ease
+	if ease == nil {
+		ease = Linear
+	}
+	if !tw.Update(ease(t)) || t >= 1 {
+		tw.finish()
+		return false
+	}
+	return true
+}
+func (tw *Tween) finish() {
+	if tw.Done != nil {
+		tw.Done()
+	}
+	close(tw.done)
+}
@@ -0,0 +1,121 @@
+// Package balance measures how well pods are spread over the nodes, and
+// how much a reschedule round changed that.
+package balance
+
+import (
+	"k8srsdraw/eventsource"
+	"sort"
+	"time"
+)
+
+// Summary is the shape of one distribution.
+type Summary struct {
+	// NodePods is the number of pods of every node.
+	NodePods map[string]int
+	// NodeNamespaces is the number of namespaces on every node.
+	NodeNamespaces map[string]int
+	// NamespaceNodes is the number of nodes every namespace is spread over.
+	NamespaceNodes map[string]int
+	// Imbalance counts the namespace groups sharing a node with another
+	// group. 0 is best: every node holds a single namespace.
+	Imbalance int
+}
+
+func Summarize(infos eventsource.Infos) Summary {
+	s := Summary{
+		NodePods:       make(map[string]int),
+		NodeNamespaces: make(map[string]int),
+		NamespaceNodes: make(map[string]int),
+	}
+	for nodeName, nodeInfo := range infos {
+		namespaces := make(map[string]bool)
+		for _, p := range nodeInfo.PodInfos {
+			namespaces[p.Namespace] = true
+		}
+		s.NodePods[nodeName] = len(nodeInfo.PodInfos)
+		s.NodeNamespaces[nodeName] = len(namespaces)
+		for ns := range namespaces {
+			s.NamespaceNodes[ns]++
+		}
+		if len(namespaces) > 1 {
+			s.Imbalance += len(namespaces) - 1
+		}
+	}
+	return s
+}
+
+// RoundDiff compares the distribution at the start and at the end of a
+// reschedule round.
+type RoundDiff struct {
+	Start  time.Time
+	End    time.Time
+	Before Summary
+	After  Summary
+}
+
+// Improved tells whether the round lowered the imbalance.
+func (d *RoundDiff) Improved() bool {
+	return d.After.Imbalance < d.Before.Imbalance
+}
+
+// Nodes returns the nodes of either side, sorted.
+func (d *RoundDiff) Nodes() []string {
+	return sortedKeys(d.Before.NodePods, d.After.NodePods)
+}
+
+// Namespaces returns the namespaces of either side, sorted.
+func (d *RoundDiff) Namespaces() []string {
+	return sortedKeys(d.Before.NamespaceNodes, d.After.NamespaceNodes)
+}
+
+func sortedKeys(a, b map[string]int) []string {
+	seen := make(map[string]bool)
+	ret := make([]string, 0, len(a))
+	for _, m := range []map[string]int{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				ret = append(ret, k)
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// RoundTracker compares a distribution across rounds. Frontends keep one
+// and hand it their copy of the cluster on every round marker; it is not
+// safe for concurrent use.
+type RoundTracker struct {
+	start  time.Time
+	before *Summary
+	last   *RoundDiff
+}
+
+// RoundStarted takes the before picture of infos.
+func (rt *RoundTracker) RoundStarted(infos eventsource.Infos) {
+	s := Summarize(infos)
+	rt.start = time.Now()
+	rt.before = &s
+}
+
+// RoundFinished takes the after picture of infos and returns the diff of
+// the round, nil for a stop marker without a start.
+func (rt *RoundTracker) RoundFinished(infos eventsource.Infos) *RoundDiff {
+	if rt.before == nil {
+		return nil
+	}
+	rt.last = &RoundDiff{
+		Start:  rt.start,
+		End:    time.Now(),
+		Before: *rt.before,
+		After:  Summarize(infos),
+	}
+	rt.before = nil
+	return rt.last
+}
+
+// Last returns the diff of the last finished round, nil if none.
+func (rt *RoundTracker) Last() *RoundDiff {
+	return rt.last
+}
@@ -0,0 +1,109 @@
+package balance
+
+import (
+	"fmt"
+	"k8srsdraw/eventsource"
+	"math"
+	"sort"
+)
+
+// Thresholds decide which nodes are painted as unhealthy.
+type Thresholds struct {
+	// MaxNamespaces is the number of namespaces a node may hold.
+	MaxNamespaces int `json:"maxNamespaces"`
+	// Capacity is the number of pods a node can hold, 0 when unknown.
+	Capacity int `json:"capacity"`
+	// MaxUtilization is the share of Capacity a node may use, 0 disables
+	// the check.
+	MaxUtilization float64 `json:"maxUtilization"`
+}
+
+// NodeThresholds is used by every frontend to color the nodes. The default
+// is the historical rule: more than one namespace on a node is bad.
+var NodeThresholds = Thresholds{MaxNamespaces: 1}
+
+// NodeOK tells whether a node holding pods pods of namespaces namespaces
+// is healthy.
+func (t Thresholds) NodeOK(pods, namespaces int) bool {
+	if namespaces > t.MaxNamespaces {
+		return false
+	}
+	if t.Capacity > 0 && t.MaxUtilization > 0 &&
+		float64(pods)/float64(t.Capacity) > t.MaxUtilization {
+		return false
+	}
+	return true
+}
+
+// Score is the cluster wide health of a distribution.
+type Score struct {
+	Nodes int `json:"nodes"`
+	Pods  int `json:"pods"`
+	// MeanPods and StdDevPods describe the pods per node, the lower the
+	// deviation the more even the spread.
+	MeanPods   float64 `json:"meanPods"`
+	StdDevPods float64 `json:"stdDevPods"`
+	// CoLocatedNodes is the number of nodes holding more than one
+	// namespace, CoLocatedPairs the number of namespace pairs sharing a
+	// node, counted once per node.
+	CoLocatedNodes int `json:"coLocatedNodes"`
+	CoLocatedPairs int `json:"coLocatedPairs"`
+	// MaxPods is the pod count of the fullest node MaxPodsNode.
+	MaxPods     int    `json:"maxPods"`
+	MaxPodsNode string `json:"maxPodsNode"`
+	// MaxUtilization is MaxPods over the capacity, 0 when the capacity is
+	// unknown.
+	MaxUtilization float64 `json:"maxUtilization"`
+	// BadNodes is the number of nodes failing the thresholds.
+	BadNodes int `json:"badNodes"`
+}
+
+// ComputeScore scores infos against t.
+func ComputeScore(infos eventsource.Infos, t Thresholds) Score {
+	s := Summarize(infos)
+	ret := Score{Nodes: len(s.NodePods)}
+	nodeNames := make([]string, 0, len(s.NodePods))
+	for name := range s.NodePods {
+		nodeNames = append(nodeNames, name)
+	}
+	// sorted so that ties on MaxPods always pick the same node
+	sort.Strings(nodeNames)
+	for _, name := range nodeNames {
+		pods, namespaces := s.NodePods[name], s.NodeNamespaces[name]
+		ret.Pods += pods
+		if pods > ret.MaxPods || ret.MaxPodsNode == "" {
+			ret.MaxPods, ret.MaxPodsNode = pods, name
+		}
+		if namespaces > 1 {
+			ret.CoLocatedNodes++
+			ret.CoLocatedPairs += namespaces * (namespaces - 1) / 2
+		}
+		if !t.NodeOK(pods, namespaces) {
+			ret.BadNodes++
+		}
+	}
+	if ret.Nodes == 0 {
+		return ret
+	}
+	ret.MeanPods = float64(ret.Pods) / float64(ret.Nodes)
+	variance := 0.0
+	for _, pods := range s.NodePods {
+		d := float64(pods) - ret.MeanPods
+		variance += d * d
+	}
+	ret.StdDevPods = math.Sqrt(variance / float64(ret.Nodes))
+	if t.Capacity > 0 {
+		ret.MaxUtilization = float64(ret.MaxPods) / float64(t.Capacity)
+	}
+	return ret
+}
+
+// String is the one line summary shown by the frontends.
+func (s Score) String() string {
+	ret := fmt.Sprintf("pods/node %.1f σ %.2f | co-located: %d nodes, %d ns pairs | fullest: %s %d pods",
+		s.MeanPods, s.StdDevPods, s.CoLocatedNodes, s.CoLocatedPairs, s.MaxPodsNode, s.MaxPods)
+	if s.MaxUtilization > 0 {
+		ret += fmt.Sprintf(" (%.0f%%)", s.MaxUtilization*100)
+	}
+	return ret + fmt.Sprintf(" | %d bad nodes", s.BadNodes)
+}
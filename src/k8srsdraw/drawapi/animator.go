@@ -0,0 +1,51 @@
+package drawapi
+
+import "time"
+
+// Animator is advanced by the frame loop of a drawer, Step is called once
+// per frame with the time of the frame and returns false once the
+// animation is over.
+type Animator interface {
+	Step(now time.Time) bool
+}
+
+// Animate adds a to the animators stepped from the next frame on.
+func (d *Drawer) Animate(a Animator) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.animators = append(d.animators, a)
+}
+
+// SetFPS changes the frame rate, a running frame loop picks it up after
+// its next tick.
+func (d *Drawer) SetFPS(fps int) {
+	if fps <= 0 {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.fps = fps
+}
+func (d *Drawer) getFPS() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.fps
+}
+
+// step advances every animator to now. The animators run without d.mutex
+// held, so that they may start new animations.
+func (d *Drawer) step(now time.Time) {
+	d.mutex.Lock()
+	animators := d.animators
+	d.animators = nil
+	d.mutex.Unlock()
+	alive := animators[:0]
+	for _, a := range animators {
+		if a.Step(now) {
+			alive = append(alive, a)
+		}
+	}
+	d.mutex.Lock()
+	d.animators = append(alive, d.animators...)
+	d.mutex.Unlock()
+}
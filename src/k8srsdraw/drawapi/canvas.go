@@ -0,0 +1,58 @@
+package drawapi
+
+import (
+	"image"
+	"image/color"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// Canvas is the back buffer of a drawer while a frame is painted. The
+// frame loop hands it to Painter.Paint with the canvas lock held, it is
+// the only way to draw, and it must not be kept after Paint returns.
+type Canvas struct {
+	rgba   *image.RGBA
+	fonts  *Fonts
+	raster *vector.Rasterizer
+}
+
+func (cv *Canvas) DrawLine(startPoint DrawPoint, endPoint DrawPoint, c color.Color) {
+	cv.StrokeLine(startPoint, endPoint, DefaultLineWidth, c)
+}
+func (cv *Canvas) DrawCircle(startPoint DrawPoint, radius int, isFill bool, c color.Color) {
+	if isFill {
+		cv.FillCircle(startPoint.F(), float64(radius), c)
+	} else {
+		cv.StrokeCircle(startPoint.F(), float64(radius), DefaultLineWidth, c)
+	}
+}
+func (cv *Canvas) DrawRect(startPoint DrawPoint, width, height int, c color.Color) {
+	cv.StrokeRoundRect(startPoint, width, height, 0, DefaultLineWidth, c)
+}
+func (cv *Canvas) FillRect(startPoint DrawPoint, width, height int, c color.Color) {
+	for x := 0; x <= width; x++ {
+		for y := 0; y <= height; y++ {
+			cv.rgba.Set(startPoint.X+x, startPoint.Y+y, c)
+		}
+	}
+}
+
+func (cv *Canvas) DrawText(startPoint DrawPoint, text string,
+	fontSize float64, c color.Color) (w int) {
+	cv.fonts.Use(fontSize, func(f font.Face) {
+		drawer := &font.Drawer{
+			Dst:  cv.rgba,
+			Src:  image.NewUniform(c),
+			Face: f,
+		}
+		drawer.Dot = fixed.Point26_6{
+			X: fixed.I(startPoint.X),
+			Y: fixed.I(startPoint.Y + int(fontSize)),
+		}
+		drawer.DrawString(text)
+		w = drawer.MeasureString(text).Floor()
+	})
+	return
+}
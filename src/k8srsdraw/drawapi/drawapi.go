@@ -5,29 +5,47 @@ package drawapi
 import (
 	"image"
 	"image/color"
-	"io/ioutil"
+	"image/draw"
+	"k8srsdraw/logging"
+	"k8srsdraw/theme"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/BurntSushi/xgbutil"
 	"github.com/BurntSushi/xgbutil/xgraphics"
 	"github.com/BurntSushi/xgbutil/xwindow"
-	"github.com/golang/freetype/truetype"
-	"golang.org/x/image/font"
-	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
 )
 
 type DrawPoint struct {
 	X, Y int
 }
 
+// DefaultFPS is the frame rate of new drawers.
+var DefaultFPS = 30
+
 type Drawer struct {
-	rgba       *image.RGBA
-	font       *truetype.Font
-	background color.Color
-	xu         *xgbutil.XUtil
-	xwin       *xwindow.Window
-	stop       chan int
-	changed    bool
+	// rgba is the back buffer frames are painted to, front the buffer
+	// shown on the window, they are swapped once a frame is complete
+	rgba   *image.RGBA
+	front  *image.RGBA
+	fonts  *Fonts
+	theme  *theme.Theme
+	xu     *xgbutil.XUtil
+	xwin   *xwindow.Window
+	scene  *Scene
+	stop   chan int
+	logger *slog.Logger
+	// canvasMutex guards the buffers and changed, it is held while a frame
+	// is painted and put on the window
+	canvasMutex sync.Mutex
+	changed     bool
+	raster      *vector.Rasterizer
+	// mutex guards what other goroutines hand to the frame loop
+	mutex     sync.Mutex
+	fps       int
+	animators []Animator
 }
 
 func Abs(x int) int {
@@ -43,39 +61,120 @@ func Max(x int, y int) int {
 	return y
 }
 
-func NewDrawer(xu *xgbutil.XUtil, xwin *xwindow.Window, rgba *image.RGBA, bg color.Color) *Drawer {
-	fontBytes, _ := ioutil.ReadFile("./luxisr.ttf")
-	f, _ := truetype.Parse(fontBytes)
+// NewDrawer returns a drawer painting to xwin with the colors of th, the
+// dark preset if th is nil. rgba is the first back buffer, it sets the size
+// of the surface. Text is drawn with DefaultFonts.
+func NewDrawer(xu *xgbutil.XUtil, xwin *xwindow.Window, rgba *image.RGBA, th *theme.Theme) *Drawer {
+	return NewDrawerWithLogger(xu, xwin, rgba, th, logging.For("drawapi"))
+}
+func NewDrawerWithLogger(xu *xgbutil.XUtil, xwin *xwindow.Window, rgba *image.RGBA, th *theme.Theme, logger *slog.Logger) *Drawer {
+	if th == nil {
+		th = theme.Default()
+	}
 	return &Drawer{
-		rgba:       rgba,
-		font:       f,
-		background: bg,
-		xu:         xu,
-		xwin:       xwin,
-		stop:       make(chan int),
-		changed:    true,
+		rgba:    rgba,
+		front:   image.NewRGBA(rgba.Bounds()),
+		fonts:   DefaultFonts(),
+		theme:   th,
+		xu:      xu,
+		xwin:    xwin,
+		scene:   NewScene(),
+		stop:    make(chan int),
+		changed: true,
+		logger:  logger,
+		fps:     DefaultFPS,
 	}
 }
+
+// Show paints a new frame if the scene changed and puts the canvas on the
+// window if it changed. Run calls it on every tick.
 func (d *Drawer) Show() {
+	d.canvasMutex.Lock()
+	defer d.canvasMutex.Unlock()
+	d.paintFrame()
 	if d.changed == true {
 		d.changed = false
-		ximg := xgraphics.NewConvert(d.xu, d.rgba)
+		ximg := xgraphics.NewConvert(d.xu, d.front)
 		// I want 'ximg' to show on 'xwin'
 		ximg.XSurfaceSet(d.xwin.Id)
 		// now show it
 		ximg.XDraw()
 		ximg.XPaint(d.xwin.Id)
+		// the window keeps its own copy of the background pixmap
+		ximg.Destroy()
 	}
 }
+
+// paintFrame paints the scene on the back buffer if it changed and swaps
+// the buffers, the caller must hold d.canvasMutex.
+func (d *Drawer) paintFrame() {
+	painters, ok := d.scene.take()
+	if !ok {
+		return
+	}
+	draw.Draw(d.rgba, d.rgba.Bounds(), image.NewUniform(d.theme.Background), image.Point{}, draw.Src)
+	cv := &Canvas{rgba: d.rgba, fonts: d.fonts, raster: d.raster}
+	for _, p := range painters {
+		p.Paint(cv)
+	}
+	d.raster = cv.raster
+	// a painter keeping the canvas can not draw on the next frame
+	cv.rgba = nil
+	d.rgba, d.front = d.front, d.rgba
+	d.changed = true
+}
+
+// Resize replaces both buffers with buffers of w x h. The scene is kept,
+// its next frame is painted to the new surface.
+func (d *Drawer) Resize(w, h int) {
+	d.canvasMutex.Lock()
+	defer d.canvasMutex.Unlock()
+	d.rgba = image.NewRGBA(image.Rect(0, 0, w, h))
+	d.front = image.NewRGBA(image.Rect(0, 0, w, h))
+	d.scene.Invalidate()
+}
+func (d *Drawer) Size() (w, h int) {
+	d.canvasMutex.Lock()
+	defer d.canvasMutex.Unlock()
+	return d.rgba.Bounds().Dx(), d.rgba.Bounds().Dy()
+}
+
+// Begin starts a batch of changes to the scene: no frame is painted until
+// the matching Commit, so that the changes show up all at once. Batches
+// nest.
+func (d *Drawer) Begin() {
+	d.scene.begin()
+}
+func (d *Drawer) Commit() {
+	d.scene.commit()
+}
+
+// Batch runs f between Begin and Commit.
+func (d *Drawer) Batch(f func()) {
+	d.Begin()
+	defer d.Commit()
+	f()
+}
+
+// Run starts the frame loop: on every tick every animator is stepped, then
+// the resulting frame is shown, so a frame never holds half of a step.
 func (d *Drawer) Run() {
 	go func() {
+		fps := d.getFPS()
+		ticker := time.NewTicker(time.Second / time.Duration(fps))
+		defer ticker.Stop()
 	ExitFor:
 		for {
 			select {
 			case <-d.stop:
 				break ExitFor
-			case <-time.After(100 * time.Millisecond):
+			case now := <-ticker.C:
+				d.step(now)
 				d.Show()
+				if f := d.getFPS(); f != fps {
+					fps = f
+					ticker.Reset(time.Second / time.Duration(fps))
+				}
 			}
 		}
 	}()
@@ -84,120 +183,20 @@ func (d *Drawer) StopRun() {
 	d.stop <- 1
 }
 func (d *Drawer) GetBackGround() color.Color {
-	return d.background
-}
-func (d *Drawer) DrawLine(startPoint DrawPoint, endPoint DrawPoint, c color.Color) {
-	dx := Abs(startPoint.X - endPoint.X)
-	dy := Abs(startPoint.Y - endPoint.Y)
-	maxD := Max(dx, dy)
-	for i := 0; i <= maxD; i++ {
-		x := int(float64(startPoint.X) + (float64(endPoint.X-startPoint.X))*(float64(i)/float64(maxD)))
-		y := int(float64(startPoint.Y) + (float64(endPoint.Y-startPoint.Y))*(float64(i)/float64(maxD)))
-		d.rgba.Set(x, y, c)
-		d.rgba.Set(x+1, y, c)
-		d.rgba.Set(x, y+1, c)
-	}
-	d.changed = true
-}
-func (d *Drawer) DrawLineWithAnimation(startPoint DrawPoint, endPoint DrawPoint, c color.Color, duration time.Duration) {
-	steps := int(int64(duration.Nanoseconds()) / (100 * int64(time.Millisecond)))
-	dx := Abs(startPoint.X - endPoint.X)
-	dy := Abs(startPoint.Y - endPoint.Y)
-	maxD := Max(dx, dy)
-	dd := (maxD / steps) + 1
-	for i := 0; i <= maxD; i++ {
-		if i > 0 && i%dd == 0 {
-			time.Sleep(100 * time.Millisecond)
-		}
-		x := int(float64(startPoint.X) + (float64(endPoint.X-startPoint.X))*(float64(i)/float64(maxD)))
-		y := int(float64(startPoint.Y) + (float64(endPoint.Y-startPoint.Y))*(float64(i)/float64(maxD)))
-		d.rgba.Set(x, y, c)
-		d.changed = true
-	}
+	return d.theme.Background
 }
-func (d *Drawer) DrawCircle(startPoint DrawPoint, radius int, isFill bool, c color.Color) {
-	if isFill {
-		for x := startPoint.X - radius; x <= startPoint.X+radius; x++ {
-			for y := startPoint.Y - radius; y <= startPoint.Y+radius; y++ {
-				tmp := (startPoint.X-x)*(startPoint.X-x) + (startPoint.Y-y)*(startPoint.Y-y)
-				if tmp <= radius*radius {
-					d.rgba.Set(x, y, c)
-				}
-			}
-		}
-	} else {
-		for x := startPoint.X - radius; x <= startPoint.X+radius; x++ {
-			for y := startPoint.Y - radius; y <= startPoint.Y+radius; y++ {
-				tmp := (startPoint.X-x)*(startPoint.X-x) + (startPoint.Y-y)*(startPoint.Y-y)
-				if radius*radius <= tmp && tmp <= (radius+1)*(radius+1) {
-					d.rgba.Set(x, y, c)
-				}
-			}
-		}
-	}
-	d.changed = true
+func (d *Drawer) GetTheme() *theme.Theme {
+	return d.theme
 }
-func (d *Drawer) DrawRect(startPoint DrawPoint, width, height int, c color.Color) {
-	d.DrawLine(startPoint, DrawPoint{startPoint.X + width, startPoint.Y}, c)
-	d.DrawLine(startPoint, DrawPoint{startPoint.X, startPoint.Y + height}, c)
-	d.DrawLine(DrawPoint{startPoint.X + width, startPoint.Y + height},
-		DrawPoint{startPoint.X + width, startPoint.Y}, c)
-	d.DrawLine(DrawPoint{startPoint.X + width, startPoint.Y + height},
-		DrawPoint{startPoint.X, startPoint.Y + height}, c)
-}
-func (d *Drawer) FillRect(startPoint DrawPoint, width, height int, c color.Color) {
-	for x := 0; x <= width; x++ {
-		for y := 0; y <= height; y++ {
-			d.rgba.Set(startPoint.X+x, startPoint.Y+y, c)
-		}
-	}
-	d.changed = true
+func (d *Drawer) Scene() *Scene {
+	return d.scene
 }
+
+// GetStrByWidth returns text cut at the end to fit in width.
 func (d *Drawer) GetStrByWidth(text string, fontSize float64, width int) string {
-	f := truetype.NewFace(d.font, &truetype.Options{
-		Size:    fontSize,
-		DPI:     72,
-		Hinting: font.HintingNone,
-	})
-	l := font.MeasureString(f, text).Floor()
-	if l <= width {
-		return text
-	}
-	bytes := []byte(text)
-	i := int(float64(len(bytes)) / float64(l) * float64(width))
-	for {
-		t := font.MeasureString(f, string(bytes[:i])+"...").Floor()
-		if t <= width {
-			if i+1 >= len(bytes) {
-				return string(bytes[:i]) + "..."
-			} else if font.MeasureString(f, string(bytes[:i+1])+"...").Floor() >= width {
-				return string(bytes[:i]) + "..."
-			}
-		}
-		if t > width {
-			i -= 1
-		} else {
-			i += 1
-		}
-	}
+	return d.fonts.Truncate(text, fontSize, width, EllipsisEnd)
 }
 
-func (d *Drawer) DrawText(startPoint DrawPoint, text string,
-	fontSize float64, c color.Color) int {
-	drawer := &font.Drawer{
-		Dst: d.rgba,
-		Src: image.NewUniform(c),
-		Face: truetype.NewFace(d.font, &truetype.Options{
-			Size:    fontSize,
-			DPI:     72,
-			Hinting: font.HintingNone,
-		}),
-	}
-	drawer.Dot = fixed.Point26_6{
-		X: fixed.I(startPoint.X),
-		Y: fixed.I(startPoint.Y + int(fontSize)),
-	}
-	drawer.DrawString(text)
-	d.changed = true
-	return drawer.MeasureString(text).Floor()
+func (d *Drawer) GetFonts() *Fonts {
+	return d.fonts
 }
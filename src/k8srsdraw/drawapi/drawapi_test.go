@@ -0,0 +1,139 @@
+package drawapi
+
+import (
+	"image"
+	"image/color"
+	"sync"
+	"testing"
+	"time"
+)
+
+// block fills its rectangle, it is the painter of the tests.
+type block struct {
+	mutex sync.Mutex
+	r     image.Rectangle
+	c     color.Color
+}
+
+func (b *block) Paint(cv *Canvas) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	cv.FillRoundRect(DrawPoint{X: b.r.Min.X, Y: b.r.Min.Y}, b.r.Dx(), b.r.Dy(), 0, b.c)
+}
+func (b *block) move(dx int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.r = b.r.Add(image.Point{X: dx})
+}
+
+// frame does what Show does short of putting the frame on a window.
+func frame(d *Drawer) {
+	d.canvasMutex.Lock()
+	defer d.canvasMutex.Unlock()
+	d.paintFrame()
+}
+func shown(d *Drawer, x, y int) color.RGBA {
+	d.canvasMutex.Lock()
+	defer d.canvasMutex.Unlock()
+	return d.front.RGBAAt(x, y)
+}
+
+type countdown struct {
+	mutex sync.Mutex
+	n     int
+}
+
+func (c *countdown) Step(now time.Time) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.n--
+	return c.n > 0
+}
+
+func TestBatch(t *testing.T) {
+	d := NewDrawer(nil, nil, image.NewRGBA(image.Rect(0, 0, 20, 20)), nil)
+	frame(d)
+	red := color.RGBA{255, 0, 0, 255}
+
+	d.Begin()
+	d.Scene().Add(&block{r: image.Rect(0, 0, 10, 10), c: red}, 0)
+	frame(d)
+	if got := shown(d, 5, 5); got == red {
+		t.Fatalf("frame painted in the middle of a batch")
+	}
+	d.Commit()
+	frame(d)
+	if got := shown(d, 5, 5); got != red {
+		t.Fatalf("got %v after the batch, want %v", got, red)
+	}
+}
+
+// TestConcurrentFrames paints frames while other goroutines change the
+// scene, in and out of batches, and resize the surface. Run it with -race.
+func TestConcurrentFrames(t *testing.T) {
+	d := NewDrawer(nil, nil, image.NewRGBA(image.Rect(0, 0, 64, 64)), nil)
+	const n = 200
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			d.step(time.Now())
+			frame(d)
+		}
+	}()
+
+	blocks := make([]*block, 4)
+	for i := range blocks {
+		blocks[i] = &block{r: image.Rect(0, 16*i, 8, 16*i+8), c: color.RGBA{0, 255, 0, 255}}
+	}
+	var writers sync.WaitGroup
+	for i, b := range blocks {
+		writers.Add(1)
+		go func(i int, b *block) {
+			defer writers.Done()
+			for j := 0; j < n; j++ {
+				d.Batch(func() {
+					d.Scene().Add(b, i)
+					b.move(1)
+					d.Scene().Invalidate()
+				})
+				if j%3 == 0 {
+					d.Scene().Remove(b)
+				}
+				d.Animate(&countdown{n: 2})
+			}
+			d.Scene().Add(b, i)
+		}(i, b)
+	}
+	writers.Add(1)
+	go func() {
+		defer writers.Done()
+		for j := 0; j < n/10; j++ {
+			d.Resize(64+j%2, 64)
+			d.Size()
+		}
+	}()
+	writers.Wait()
+	close(done)
+	wg.Wait()
+
+	if got := d.Scene().Len(); got != len(blocks) {
+		t.Fatalf("got %d painters, want %d", got, len(blocks))
+	}
+	d.Resize(256, 64)
+	frame(d)
+	for _, b := range blocks {
+		p := b.r.Min.Add(image.Point{X: 1, Y: 1})
+		if got := shown(d, p.X, p.Y); got != (color.RGBA{0, 255, 0, 255}) {
+			t.Fatalf("got %v at %v, want the block", got, p)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package drawapi
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"k8srsdraw/logging"
+	"os"
+	"sync"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+//go:embed luxisr.ttf
+var embeddedFont []byte
+
+// SystemFallbacks are the fonts LoadFonts appends, when they are
+// installed, for the glyphs of pod names the other fonts lack: CJK first,
+// then wide coverage of the other scripts.
+var SystemFallbacks = []string{
+	"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf",
+	"/usr/share/fonts/truetype/wqy/wqy-microhei.ttf",
+	"/usr/share/fonts/truetype/arphic/uming.ttf",
+	"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/TTF/DejaVuSans.ttf",
+}
+
+// Fonts is a chain of fonts: every glyph is taken from the first font
+// which has it. Faces are made once per size, and the widths of strings
+// are cached.
+type Fonts struct {
+	mutex  sync.Mutex
+	fonts  []*truetype.Font
+	faces  map[float64]font.Face
+	widths map[measureKey]int
+}
+
+func NewFonts(primary *truetype.Font, fallbacks ...*truetype.Font) *Fonts {
+	return &Fonts{
+		fonts:  append([]*truetype.Font{primary}, fallbacks...),
+		faces:  make(map[float64]font.Face),
+		widths: make(map[measureKey]int),
+	}
+}
+
+// LoadFonts loads primary, the embedded font if it is empty, followed by
+// fallbacks and the installed SystemFallbacks. The fonts given must load,
+// the system ones are skipped when missing or broken.
+func LoadFonts(primary string, fallbacks []string) (*Fonts, error) {
+	var p *truetype.Font
+	var err error
+	if primary == "" {
+		p, err = truetype.Parse(embeddedFont)
+	} else {
+		p, err = loadFont(primary)
+	}
+	if err != nil {
+		return nil, err
+	}
+	chain := make([]*truetype.Font, 0, len(fallbacks)+len(SystemFallbacks))
+	for _, path := range fallbacks {
+		f, err := loadFont(path)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, f)
+	}
+	logger := logging.For("drawapi")
+	for _, path := range SystemFallbacks {
+		f, err := loadFont(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			logger.Warn("skip fallback font", "err", err)
+			continue
+		}
+		chain = append(chain, f)
+	}
+	return NewFonts(p, chain...), nil
+}
+func loadFont(path string) (*truetype.Font, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := truetype.Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("parse font %s: %w", path, err)
+	}
+	return f, nil
+}
+
+var (
+	defaultFontsMutex sync.Mutex
+	defaultFonts      *Fonts
+)
+
+// DefaultFonts returns the fonts of new drawers, the embedded font and the
+// installed SystemFallbacks unless SetDefaultFonts was called.
+func DefaultFonts() *Fonts {
+	defaultFontsMutex.Lock()
+	defer defaultFontsMutex.Unlock()
+	if defaultFonts == nil {
+		f, err := LoadFonts("", nil)
+		if err != nil {
+			panic(fmt.Sprintf("embedded font: %v", err))
+		}
+		defaultFonts = f
+	}
+	return defaultFonts
+}
+func SetDefaultFonts(f *Fonts) {
+	defaultFontsMutex.Lock()
+	defer defaultFontsMutex.Unlock()
+	defaultFonts = f
+}
+
+// Use calls fn with the face of size. Faces are not safe for concurrent
+// use, fn must not keep it.
+func (f *Fonts) Use(size float64, fn func(face font.Face)) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	face, ok := f.faces[size]
+	if !ok {
+		ff := &fallbackFace{fonts: f.fonts, faces: make([]font.Face, len(f.fonts))}
+		for i, ft := range f.fonts {
+			ff.faces[i] = truetype.NewFace(ft, &truetype.Options{
+				Size:    size,
+				DPI:     72,
+				Hinting: font.HintingNone,
+			})
+		}
+		face = ff
+		f.faces[size] = face
+	}
+	fn(face)
+}
+
+// Measure returns the width of text in pixels.
+func (f *Fonts) Measure(text string, size float64) (w int) {
+	f.Use(size, func(face font.Face) {
+		w = f.measure(face, size, text)
+	})
+	return
+}
+
+// fallbackFace draws every rune with the first face whose font has it, the
+// first face if none has.
+type fallbackFace struct {
+	fonts []*truetype.Font
+	faces []font.Face
+}
+
+func (f *fallbackFace) pick(r rune) font.Face {
+	for i, ft := range f.fonts {
+		if ft.Index(r) != 0 {
+			return f.faces[i]
+		}
+	}
+	return f.faces[0]
+}
+func (f *fallbackFace) Close() error {
+	for _, face := range f.faces {
+		face.Close()
+	}
+	return nil
+}
+func (f *fallbackFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	return f.pick(r).Glyph(dot, r)
+}
+func (f *fallbackFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	return f.pick(r).GlyphBounds(r)
+}
+func (f *fallbackFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	return f.pick(r).GlyphAdvance(r)
+}
+
+// Kern only applies between runes of the same font.
+func (f *fallbackFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	face := f.pick(r0)
+	if face != f.pick(r1) {
+		return 0
+	}
+	return face.Kern(r0, r1)
+}
+func (f *fallbackFace) Metrics() font.Metrics {
+	return f.faces[0].Metrics()
+}
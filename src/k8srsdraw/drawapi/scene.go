@@ -0,0 +1,122 @@
+package drawapi
+
+import (
+	"sort"
+	"sync"
+)
+
+// Painter is an element of a Scene, it paints itself with the Draw, Fill
+// and Stroke methods of the canvas of the frame. Painters are map keys of the scene, so
+// they must be comparable, typically pointers.
+type Painter interface {
+	Paint(cv *Canvas)
+}
+
+type sceneItem struct {
+	painter Painter
+	z       int
+	seq     uint64
+}
+
+// Scene is what a Drawer shows: the painters registered with Add, painted
+// by increasing z and then in the order they were added. Every frame is
+// painted from scratch on the background, so that removing or moving one
+// element never damages the others.
+type Scene struct {
+	mutex sync.Mutex
+	items map[Painter]*sceneItem
+	seq   uint64
+	dirty bool
+	// batch counts the open Begin calls, no frame is taken meanwhile
+	batch int
+}
+
+func NewScene() *Scene {
+	return &Scene{
+		items: make(map[Painter]*sceneItem),
+		dirty: true,
+	}
+}
+
+// Add registers p at z, or moves it to z if it is registered already.
+func (s *Scene) Add(p Painter, z int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if item, ok := s.items[p]; ok {
+		item.z = z
+	} else {
+		s.seq++
+		s.items[p] = &sceneItem{painter: p, z: z, seq: s.seq}
+	}
+	s.dirty = true
+}
+func (s *Scene) Remove(p Painter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.items[p]; ok {
+		delete(s.items, p)
+		s.dirty = true
+	}
+}
+func (s *Scene) Contains(p Painter) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.items[p]
+	return ok
+}
+func (s *Scene) Clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.items = make(map[Painter]*sceneItem)
+	s.dirty = true
+}
+func (s *Scene) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.items)
+}
+
+// Invalidate asks for a new frame, painters call it when they changed.
+func (s *Scene) Invalidate() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.dirty = true
+}
+
+func (s *Scene) begin() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.batch++
+}
+func (s *Scene) commit() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.batch > 0 {
+		s.batch--
+	}
+}
+
+// take returns the painters in paint order if a new frame is due.
+func (s *Scene) take() ([]Painter, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.dirty || s.batch > 0 {
+		return nil, false
+	}
+	s.dirty = false
+	items := make([]*sceneItem, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].z != items[j].z {
+			return items[i].z < items[j].z
+		}
+		return items[i].seq < items[j].seq
+	})
+	ret := make([]Painter, len(items))
+	for i, item := range items {
+		ret[i] = item.painter
+	}
+	return ret, true
+}
@@ -0,0 +1,244 @@
+package drawapi
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Ellipsis is where Truncate cuts text which does not fit.
+type Ellipsis int
+
+const (
+	EllipsisEnd Ellipsis = iota
+	EllipsisStart
+	EllipsisMiddle
+)
+
+// Align places text in its box, left or top for AlignStart.
+type Align int
+
+const (
+	AlignStart Align = iota
+	AlignCenter
+	AlignEnd
+)
+
+const ellipsis = "..."
+
+// MeasureCacheSize is how many widths of strings Fonts remembers, the
+// cache is emptied when it is full.
+var MeasureCacheSize = 4096
+
+// TextLayout is how DrawTextBox lays text out in its box.
+type TextLayout struct {
+	HAlign Align
+	VAlign Align
+	// Wrap breaks the text between words, or anywhere in words too long
+	// for a line. Without it every line of the text is truncated.
+	Wrap bool
+	// MaxLines limits the lines on top of the height of the box, the
+	// last line shown ends with an ellipsis. 0 means no limit.
+	MaxLines int
+	Ellipsis Ellipsis
+	// LineSpacing is the height of a line relative to the font size,
+	// 1.2 if 0.
+	LineSpacing float64
+}
+
+// LineHeight is the distance between two lines of text of size.
+func (l TextLayout) LineHeight(size float64) int {
+	spacing := l.LineSpacing
+	if spacing == 0 {
+		spacing = 1.2
+	}
+	return int(math.Ceil(size * spacing))
+}
+
+// TextLine is a line of text laid out by Layout, X and Y are the left end
+// of its baseline.
+type TextLine struct {
+	Text  string
+	X, Y  int
+	Width int
+}
+
+type measureKey struct {
+	text string
+	size float64
+}
+
+// measure returns the width of text, the caller must hold f.mutex.
+func (f *Fonts) measure(face font.Face, size float64, text string) int {
+	key := measureKey{text, size}
+	if w, ok := f.widths[key]; ok {
+		return w
+	}
+	if len(f.widths) >= MeasureCacheSize {
+		f.widths = make(map[measureKey]int)
+	}
+	w := font.MeasureString(face, text).Ceil()
+	f.widths[key] = w
+	return w
+}
+
+// Truncate returns text cut at e with an ellipsis to fit in width, text if
+// it fits, and "" if not even the ellipsis does.
+func (f *Fonts) Truncate(text string, size float64, width int, e Ellipsis) (ret string) {
+	f.Use(size, func(face font.Face) {
+		ret = f.truncate(face, size, text, width, e)
+	})
+	return
+}
+func (f *Fonts) truncate(face font.Face, size float64, text string, width int, e Ellipsis) string {
+	if f.measure(face, size, text) <= width {
+		return text
+	}
+	if f.measure(face, size, ellipsis) > width {
+		return ""
+	}
+	runes := []rune(text)
+	keep := func(n int) string {
+		switch e {
+		case EllipsisStart:
+			return ellipsis + string(runes[len(runes)-n:])
+		case EllipsisMiddle:
+			return string(runes[:(n+1)/2]) + ellipsis + string(runes[len(runes)-n/2:])
+		}
+		return string(runes[:n]) + ellipsis
+	}
+	// the most runes which fit, keep(lo) always does
+	lo, hi := 0, len(runes)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if f.measure(face, size, keep(mid)) <= width {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return keep(lo)
+}
+
+// Wrap breaks text into lines no wider than width, at spaces and line
+// breaks, and inside words wider than width.
+func (f *Fonts) Wrap(text string, size float64, width int) (lines []string) {
+	f.Use(size, func(face font.Face) {
+		lines = f.wrap(face, size, text, width)
+	})
+	return
+}
+func (f *Fonts) wrap(face font.Face, size float64, text string, width int) []string {
+	lines := make([]string, 0)
+	for _, para := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			if line != "" && f.measure(face, size, line+" "+word) <= width {
+				line += " " + word
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			// words too long for a line of their own are split anywhere
+			for word != "" && f.measure(face, size, word) > width {
+				n := f.fit(face, size, []rune(word), width)
+				lines = append(lines, string([]rune(word)[:n]))
+				word = string([]rune(word)[n:])
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fit returns how many of the first runes fit in width, at least one so
+// that wrapping always moves on.
+func (f *Fonts) fit(face font.Face, size float64, runes []rune, width int) int {
+	lo, hi := 1, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if f.measure(face, size, string(runes[:mid])) <= width {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo
+}
+
+// Layout places text in box following l.
+func (f *Fonts) Layout(text string, size float64, box image.Rectangle, l TextLayout) (ret []TextLine) {
+	f.Use(size, func(face font.Face) {
+		ret = f.layout(face, size, text, box, l)
+	})
+	return
+}
+func (f *Fonts) layout(face font.Face, size float64, text string, box image.Rectangle, l TextLayout) []TextLine {
+	lineHeight := l.LineHeight(size)
+	maxLines := box.Dy() / lineHeight
+	if l.MaxLines > 0 && l.MaxLines < maxLines {
+		maxLines = l.MaxLines
+	}
+	if maxLines < 1 {
+		maxLines = 1
+	}
+
+	var lines []string
+	if l.Wrap {
+		lines = f.wrap(face, size, text, box.Dx())
+	} else {
+		lines = strings.Split(text, "\n")
+	}
+	if len(lines) > maxLines {
+		// what does not fit goes to the last line, to be cut
+		lines[maxLines-1] = strings.Join(lines[maxLines-1:], " ")
+		lines = lines[:maxLines]
+	}
+
+	y := box.Min.Y
+	switch l.VAlign {
+	case AlignCenter:
+		y += (box.Dy() - len(lines)*lineHeight) / 2
+	case AlignEnd:
+		y += box.Dy() - len(lines)*lineHeight
+	}
+	// the baseline sits in the middle of the space the line leaves
+	m := face.Metrics()
+	baseline := (lineHeight-(m.Ascent+m.Descent).Ceil())/2 + m.Ascent.Ceil()
+
+	ret := make([]TextLine, len(lines))
+	for i, line := range lines {
+		line = f.truncate(face, size, line, box.Dx(), l.Ellipsis)
+		w := f.measure(face, size, line)
+		x := box.Min.X
+		switch l.HAlign {
+		case AlignCenter:
+			x += (box.Dx() - w) / 2
+		case AlignEnd:
+			x += box.Dx() - w
+		}
+		ret[i] = TextLine{Text: line, X: x, Y: y + i*lineHeight + baseline, Width: w}
+	}
+	return ret
+}
+
+// DrawTextBox draws text in box laid out following l.
+func (cv *Canvas) DrawTextBox(box image.Rectangle, text string, fontSize float64, l TextLayout, c color.Color) {
+	cv.fonts.Use(fontSize, func(face font.Face) {
+		drawer := &font.Drawer{
+			Dst:  cv.rgba,
+			Src:  image.NewUniform(c),
+			Face: face,
+		}
+		for _, line := range cv.fonts.layout(face, fontSize, text, box, l) {
+			drawer.Dot = fixed.P(line.X, line.Y)
+			drawer.DrawString(line.Text)
+		}
+	})
+}
@@ -0,0 +1,283 @@
+package drawapi
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/vector"
+)
+
+// DefaultLineWidth is the width of DrawLine, DrawRect and DrawCircle.
+var DefaultLineWidth = 2.0
+
+// PointF is a point between pixels, the center of pixel (x, y) being
+// (x+0.5, y+0.5).
+type PointF struct {
+	X, Y float64
+}
+
+func (p DrawPoint) F() PointF {
+	return PointF{float64(p.X), float64(p.Y)}
+}
+func (p PointF) add(q PointF) PointF {
+	return PointF{p.X + q.X, p.Y + q.Y}
+}
+func (p PointF) sub(q PointF) PointF {
+	return PointF{p.X - q.X, p.Y - q.Y}
+}
+func (p PointF) mul(k float64) PointF {
+	return PointF{p.X * k, p.Y * k}
+}
+func (p PointF) len() float64 {
+	return math.Hypot(p.X, p.Y)
+}
+
+// outline is a shape as closed polygons. Overlapping polygons of the same
+// orientation are painted once, polygons of opposite orientations cancel
+// out, which is how rings are made.
+type outline [][]PointF
+
+// fill paints o antialiased with c.
+func (cv *Canvas) fill(o outline, c color.Color) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, poly := range o {
+		for _, p := range poly {
+			minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+			maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+		}
+	}
+	if math.IsInf(minX, 0) {
+		return
+	}
+	r := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1).Intersect(cv.rgba.Bounds())
+	if r.Empty() {
+		return
+	}
+	if cv.raster == nil {
+		cv.raster = vector.NewRasterizer(r.Dx(), r.Dy())
+	} else {
+		cv.raster.Reset(r.Dx(), r.Dy())
+	}
+	ox, oy := float64(r.Min.X), float64(r.Min.Y)
+	for _, poly := range o {
+		if len(poly) < 3 {
+			continue
+		}
+		cv.raster.MoveTo(float32(poly[0].X-ox), float32(poly[0].Y-oy))
+		for _, p := range poly[1:] {
+			cv.raster.LineTo(float32(p.X-ox), float32(p.Y-oy))
+		}
+		cv.raster.ClosePath()
+	}
+	cv.raster.Draw(cv.rgba, r, image.NewUniform(c), image.Point{})
+}
+
+// segment is the outline of the line from a to b, width wide, without
+// caps.
+func segment(a, b PointF, width float64) []PointF {
+	dir := b.sub(a)
+	l := dir.len()
+	if l == 0 {
+		return nil
+	}
+	n := PointF{-dir.Y / l, dir.X / l}.mul(width / 2)
+	return []PointF{a.add(n), b.add(n), b.sub(n), a.sub(n)}
+}
+
+// circle approximates the circle of center c and radius r, clockwise on
+// screen unless ccw.
+func circle(c PointF, r float64, ccw bool) []PointF {
+	n := int(math.Ceil(2 * math.Pi * r / 3))
+	if n < 12 {
+		n = 12
+	} else if n > 128 {
+		n = 128
+	}
+	ret := make([]PointF, n)
+	for i := range ret {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		if ccw {
+			a = -a
+		}
+		ret[i] = PointF{c.X + r*math.Cos(a), c.Y + r*math.Sin(a)}
+	}
+	return ret
+}
+
+// polyline is the outline of the lines through pts, width wide, with round
+// joins.
+func polyline(pts []PointF, width float64, closed bool) outline {
+	o := make(outline, 0, 2*len(pts))
+	for i := 0; i+1 < len(pts); i++ {
+		o = append(o, orient(segment(pts[i], pts[i+1], width)))
+	}
+	if closed && len(pts) > 2 {
+		o = append(o, orient(segment(pts[len(pts)-1], pts[0], width)))
+	}
+	if width > 1.5 {
+		for i, p := range pts {
+			if closed || (i > 0 && i < len(pts)-1) {
+				o = append(o, circle(p, width/2, false))
+			}
+		}
+	}
+	return o
+}
+
+// orient makes poly clockwise on screen, so that it adds up with the other
+// polygons of an outline instead of cancelling them.
+func orient(poly []PointF) []PointF {
+	area := 0.0
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		area += p.X*q.Y - q.X*p.Y
+	}
+	if area < 0 {
+		for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+			poly[i], poly[j] = poly[j], poly[i]
+		}
+	}
+	return poly
+}
+
+// roundRect is the outline of the rectangle at x, y of w x h, with corners
+// rounded by radius.
+func roundRect(x, y, w, h, radius float64) []PointF {
+	radius = math.Max(0, math.Min(radius, math.Min(w, h)/2))
+	if radius == 0 {
+		return []PointF{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}}
+	}
+	steps := int(math.Max(2, math.Ceil(radius/2)))
+	corners := []struct {
+		c     PointF
+		start float64
+	}{
+		{PointF{x + w - radius, y + radius}, -math.Pi / 2},
+		{PointF{x + w - radius, y + h - radius}, 0},
+		{PointF{x + radius, y + h - radius}, math.Pi / 2},
+		{PointF{x + radius, y + radius}, math.Pi},
+	}
+	ret := make([]PointF, 0, 4*(steps+1))
+	for _, corner := range corners {
+		for i := 0; i <= steps; i++ {
+			a := corner.start + math.Pi/2*float64(i)/float64(steps)
+			ret = append(ret, PointF{corner.c.X + radius*math.Cos(a), corner.c.Y + radius*math.Sin(a)})
+		}
+	}
+	return ret
+}
+
+// Bezier returns n+1 points of the cubic bezier curve from p0 to p3 with
+// the control points p1 and p2.
+func Bezier(p0, p1, p2, p3 PointF, n int) []PointF {
+	ret := make([]PointF, n+1)
+	for i := range ret {
+		t := float64(i) / float64(n)
+		u := 1 - t
+		ret[i] = p0.mul(u * u * u).add(p1.mul(3 * u * u * t)).add(p2.mul(3 * u * t * t)).add(p3.mul(t * t * t))
+	}
+	return ret
+}
+
+// bezierSteps is how many segments make a curve of the given control
+// polygon look smooth.
+func bezierSteps(p0, p1, p2, p3 PointF) int {
+	l := p1.sub(p0).len() + p2.sub(p1).len() + p3.sub(p2).len()
+	return int(math.Max(8, math.Min(200, l/4)))
+}
+
+// The methods below paint antialiased shapes with a width in pixels.
+
+func (cv *Canvas) StrokeLine(startPoint, endPoint DrawPoint, width float64, c color.Color) {
+	cv.fill(outline{segment(startPoint.F(), endPoint.F(), width)}, c)
+}
+
+// StrokeDashedLine alternates dash long strokes and gap long holes, it
+// draws nothing unless dash is positive and gap is not negative.
+func (cv *Canvas) StrokeDashedLine(startPoint, endPoint DrawPoint, width, dash, gap float64, c color.Color) {
+	a, b := startPoint.F(), endPoint.F()
+	l := b.sub(a).len()
+	if l == 0 || dash <= 0 || gap < 0 {
+		return
+	}
+	dir := b.sub(a).mul(1 / l)
+	o := make(outline, 0)
+	for s := 0.0; s < l; s += dash + gap {
+		e := math.Min(s+dash, l)
+		o = append(o, segment(a.add(dir.mul(s)), a.add(dir.mul(e)), width))
+	}
+	cv.fill(o, c)
+}
+
+// StrokePolyline draws the lines through pts.
+func (cv *Canvas) StrokePolyline(pts []PointF, width float64, c color.Color) {
+	cv.fill(polyline(pts, width, false), c)
+}
+
+// StrokeBezier draws the cubic bezier curve from p0 to p3 with the control
+// points p1 and p2.
+func (cv *Canvas) StrokeBezier(p0, p1, p2, p3 PointF, width float64, c color.Color) {
+	cv.StrokePolyline(Bezier(p0, p1, p2, p3, bezierSteps(p0, p1, p2, p3)), width, c)
+}
+
+// ArrowHead returns the triangle of a head of size pointing at tip, coming
+// from the direction of from.
+func ArrowHead(from, tip PointF, size float64) []PointF {
+	dir := tip.sub(from)
+	l := dir.len()
+	if l == 0 {
+		return nil
+	}
+	dir = dir.mul(1 / l)
+	n := PointF{-dir.Y, dir.X}
+	base := tip.sub(dir.mul(size))
+	return []PointF{tip, base.add(n.mul(size / 2)), base.sub(n.mul(size / 2))}
+}
+
+// DrawArrow draws a line from startPoint to endPoint ending with a head of
+// size.
+func (cv *Canvas) DrawArrow(startPoint, endPoint DrawPoint, width, size float64, c color.Color) {
+	a, b := startPoint.F(), endPoint.F()
+	l := b.sub(a).len()
+	if l == 0 {
+		return
+	}
+	// stop the shaft inside the head, its end would stick out of the tip
+	shaftEnd := b.sub(b.sub(a).mul(math.Min(size/2, l) / l))
+	cv.fill(outline{orient(segment(a, shaftEnd, width)), orient(ArrowHead(a, b, size))}, c)
+}
+
+func (cv *Canvas) FillPolygon(pts []PointF, c color.Color) {
+	cv.fill(outline{pts}, c)
+}
+func (cv *Canvas) StrokePolygon(pts []PointF, width float64, c color.Color) {
+	cv.fill(polyline(pts, width, true), c)
+}
+func (cv *Canvas) FillCircle(center PointF, radius float64, c color.Color) {
+	cv.fill(outline{circle(center, radius, false)}, c)
+}
+func (cv *Canvas) StrokeCircle(center PointF, radius, width float64, c color.Color) {
+	cv.fill(outline{circle(center, radius+width/2, false), circle(center, math.Max(0, radius-width/2), true)}, c)
+}
+func (cv *Canvas) FillRoundRect(startPoint DrawPoint, width, height int, radius float64, c color.Color) {
+	cv.fill(outline{roundRect(float64(startPoint.X), float64(startPoint.Y), float64(width), float64(height), radius)}, c)
+}
+
+// StrokeRoundRect draws the border of the rectangle, centered on its edges.
+func (cv *Canvas) StrokeRoundRect(startPoint DrawPoint, width, height int, radius, lineWidth float64, c color.Color) {
+	x, y, w, h := float64(startPoint.X), float64(startPoint.Y), float64(width), float64(height)
+	hw := lineWidth / 2
+	outer := roundRect(x-hw, y-hw, w+lineWidth, h+lineWidth, radius+hw)
+	if w <= lineWidth || h <= lineWidth {
+		cv.fill(outline{outer}, c)
+		return
+	}
+	inner := roundRect(x+hw, y+hw, w-lineWidth, h-lineWidth, radius-hw)
+	for i, j := 0, len(inner)-1; i < j; i, j = i+1, j-1 {
+		inner[i], inner[j] = inner[j], inner[i]
+	}
+	cv.fill(outline{outer, inner}, c)
+}
@@ -0,0 +1,172 @@
+package drawapi
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// area is the signed area of poly, positive when it is clockwise on
+// screen.
+func area(poly []PointF) float64 {
+	a := 0.0
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		a += p.X*q.Y - q.X*p.Y
+	}
+	return a / 2
+}
+
+func TestSegment(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  PointF
+		width float64
+		area  float64
+	}{
+		{"horizontal", PointF{0, 0}, PointF{10, 0}, 2, 20},
+		{"vertical", PointF{0, 0}, PointF{0, 5}, 4, 20},
+		{"diagonal", PointF{1, 1}, PointF{4, 5}, 1, 5},
+		{"empty", PointF{3, 3}, PointF{3, 3}, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seg := segment(tt.a, tt.b, tt.width)
+			if tt.area == 0 {
+				if seg != nil {
+					t.Fatalf("got %v, want nil", seg)
+				}
+				return
+			}
+			if got := math.Abs(area(seg)); math.Abs(got-tt.area) > 1e-9 {
+				t.Fatalf("got area %v, want %v", got, tt.area)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	square := func() []PointF { return []PointF{{0, 0}, {4, 0}, {4, 4}, {0, 4}} }
+	reversed := func() []PointF { return []PointF{{0, 4}, {4, 4}, {4, 0}, {0, 0}} }
+	tests := []struct {
+		name string
+		poly []PointF
+	}{
+		{"clockwise", square()},
+		{"counterclockwise", reversed()},
+		{"segment", segment(PointF{0, 0}, PointF{10, 0}, 2)},
+		{"reversed segment", segment(PointF{10, 0}, PointF{0, 0}, 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := math.Abs(area(tt.poly))
+			got := area(orient(tt.poly))
+			if got <= 0 || math.Abs(got-before) > 1e-9 {
+				t.Fatalf("got area %v, want %v", got, before)
+			}
+		})
+	}
+}
+
+func TestRoundRect(t *testing.T) {
+	tests := []struct {
+		name       string
+		x, y, w, h float64
+		radius     float64
+		points     int
+	}{
+		{"square corners", 1, 2, 10, 6, 0, 4},
+		{"negative radius", 1, 2, 10, 6, -3, 4},
+		{"rounded", 0, 0, 10, 6, 2, 12},
+		{"radius clamped to half the height", 0, 0, 10, 6, 100, 4 * (2 + 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poly := roundRect(tt.x, tt.y, tt.w, tt.h, tt.radius)
+			if len(poly) != tt.points {
+				t.Fatalf("got %d points, want %d", len(poly), tt.points)
+			}
+			for _, p := range poly {
+				if p.X < tt.x-1e-9 || p.X > tt.x+tt.w+1e-9 || p.Y < tt.y-1e-9 || p.Y > tt.y+tt.h+1e-9 {
+					t.Fatalf("point %v out of the rectangle", p)
+				}
+			}
+			if got := area(poly); got <= 0 || got > tt.w*tt.h+1e-9 {
+				t.Fatalf("got area %v, want in (0, %v]", got, tt.w*tt.h)
+			}
+		})
+	}
+}
+
+func newTestCanvas(w, h int) *Canvas {
+	return &Canvas{rgba: image.NewRGBA(image.Rect(0, 0, w, h)), fonts: DefaultFonts()}
+}
+
+// alpha returns the coverage of pixel x, y painted with opaque white.
+func alpha(cv *Canvas, x, y int) uint8 {
+	return cv.rgba.RGBAAt(x, y).A
+}
+
+func TestStrokeCircle(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+	tests := []struct {
+		name   string
+		x, y   int
+		radius float64
+		width  float64
+		full   bool
+	}{
+		// pixel x, y covers x to x+1, the ring runs from 4 to 8
+		{"on the ring", 16, 10, 6, 4, true},
+		{"on the ring above", 10, 3, 6, 4, true},
+		{"center", 10, 10, 6, 4, false},
+		{"inside", 12, 10, 6, 4, false},
+		{"outside", 19, 10, 6, 4, false},
+		{"corner", 0, 0, 6, 4, false},
+		{"wider than the radius", 10, 10, 2, 6, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cv := newTestCanvas(20, 20)
+			cv.StrokeCircle(PointF{10, 10}, tt.radius, tt.width, white)
+			got := alpha(cv, tt.x, tt.y)
+			if tt.full && got != 255 {
+				t.Fatalf("got alpha %d at %d,%d, want 255", got, tt.x, tt.y)
+			}
+			if !tt.full && got != 0 {
+				t.Fatalf("got alpha %d at %d,%d, want 0", got, tt.x, tt.y)
+			}
+		})
+	}
+}
+
+func TestStrokeDashedLine(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+	tests := []struct {
+		name      string
+		dash, gap float64
+		covered   int
+	}{
+		{"dashes", 2, 2, 10},
+		{"no gap", 4, 0, 20},
+		{"no dash", 0, 2, 0},
+		{"negative gap", 2, -2, 0},
+		{"negative dash and gap", -1, -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cv := newTestCanvas(20, 4)
+			cv.StrokeDashedLine(DrawPoint{X: 0, Y: 2}, DrawPoint{X: 20, Y: 2}, 2, tt.dash, tt.gap, white)
+			covered := 0
+			for x := 0; x < 20; x++ {
+				if alpha(cv, x, 1) == 255 {
+					covered++
+				}
+			}
+			if covered != tt.covered {
+				t.Fatalf("got %d pixels covered, want %d", covered, tt.covered)
+			}
+		})
+	}
+}
@@ -1,22 +1,46 @@
 package eventhandler
 
 import (
-	"image/color"
-	"k8srsdraw/socketclient"
+	"k8srsdraw/eventsource"
+	"k8srsdraw/theme"
 	"k8srsdraw/window"
+	"time"
 )
 
 type DrawEventHandle struct {
 	w *window.Window
 }
 
-func NewDrawEventHandle(w, h int) *DrawEventHandle {
-	return &DrawEventHandle{
-		w: window.NewWindow(w, h, color.RGBA{0x00, 0x00, 0x00, 0xff}),
+func NewDrawEventHandle(w, h int, th *theme.Theme) (*DrawEventHandle, error) {
+	win, err := window.NewWindow(w, h, th)
+	if err != nil {
+		return nil, err
 	}
+	go win.WaitEvent()
+	return &DrawEventHandle{w: win}, nil
 }
 
-func (deh *DrawEventHandle) Init(infos socketclient.Infos) {
+// OnKey calls f whenever key is pressed in the window.
+func (deh *DrawEventHandle) OnKey(key string, f func()) {
+	deh.w.OnKey(key, f)
+}
+
+// SetStaleThreshold sets after how long without data the window warns.
+func (deh *DrawEventHandle) SetStaleThreshold(d time.Duration) {
+	deh.w.SetStaleThreshold(d)
+}
+
+func (deh *DrawEventHandle) Connected() {
+	deh.w.SetConnected(true)
+}
+func (deh *DrawEventHandle) Disconnected(err error) {
+	deh.w.SetConnected(false)
+}
+func (deh *DrawEventHandle) Alive(t time.Time) {
+	deh.w.SetLastSeen(t)
+}
+
+func (deh *DrawEventHandle) Init(infos eventsource.Infos) {
 	for _, nodeInfo := range infos {
 		deh.w.AddNode(nodeInfo.NodeName)
 
@@ -38,25 +62,20 @@ func (deh *DrawEventHandle) DeletePod(nodeName, podNamespace, podName string) {
 	deh.w.DeletePod(nodeName, podNamespace, podName)
 }
 
-func (deh *DrawEventHandle) ReschedulePod(fromNodeName, toNodeName, podNamespace, fromPodName, toPodName string) {
-	deh.w.MovePodFromTo(fromNodeName, toNodeName, podNamespace, fromPodName, toPodName)
+func (deh *DrawEventHandle) ReschedulePod(ev eventsource.RescheduleEvent) {
+	if !ev.Success {
+		return
+	}
+	deh.w.MovePodFromTo(ev.FromNodeName, ev.ToNodeName, ev.Namespace, ev.FromPodName, ev.ToPodName)
 }
 
-func (deh *DrawEventHandle) GetCurNodeInfos() socketclient.Infos {
-	ret := make(map[string]*socketclient.NodeInfos)
-	nodes := deh.w.GetNodeList()
-	for _, node := range nodes {
-		t := &socketclient.NodeInfos{NodeName: node.Name,
-			PodInfos: make([]socketclient.PodInfos, 0),
-		}
-		ret[node.Name] = t
-		for _, p := range node.Pods {
-			for name, _ := range p.Names {
-				t.PodInfos = append(t.PodInfos,
-					socketclient.PodInfos{Name: name, Namespace: p.Namespace})
-			}
+func (deh *DrawEventHandle) GetCurNodeInfos() eventsource.Infos {
+	return deh.w.GetInfos()
+}
 
-		}
-	}
-	return ret
+func (deh *DrawEventHandle) RoundStarted() {
+	deh.w.RoundStarted()
+}
+func (deh *DrawEventHandle) RoundFinished() {
+	deh.w.RoundFinished()
 }
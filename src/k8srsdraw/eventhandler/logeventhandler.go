@@ -0,0 +1,68 @@
+package eventhandler
+
+import (
+	"k8srsdraw/eventsource"
+	"k8srsdraw/logging"
+	"log/slog"
+	"sync"
+)
+
+// LogEventHandle logs every call, handy when no window is available.
+type LogEventHandle struct {
+	mutex  sync.Mutex
+	infos  eventsource.Infos
+	logger *slog.Logger
+}
+
+func NewLogEventHandle() *LogEventHandle {
+	return &LogEventHandle{
+		infos:  make(eventsource.Infos),
+		logger: logging.For("events"),
+	}
+}
+
+func (leh *LogEventHandle) Init(infos eventsource.Infos) {
+	leh.mutex.Lock()
+	defer leh.mutex.Unlock()
+	leh.infos = infos.Copy()
+	podNum := 0
+	for _, nodeInfo := range infos {
+		podNum += len(nodeInfo.PodInfos)
+	}
+	leh.logger.Info("init", "nodes", len(infos), "pods", podNum)
+}
+func (leh *LogEventHandle) AddNode(nodeName string) {
+	leh.mutex.Lock()
+	defer leh.mutex.Unlock()
+	leh.infos.AddNode(nodeName)
+	leh.logger.Info("add node", "node", nodeName)
+}
+func (leh *LogEventHandle) DeleteNode(nodeName string) {
+	leh.mutex.Lock()
+	defer leh.mutex.Unlock()
+	leh.infos.DeleteNode(nodeName)
+	leh.logger.Info("delete node", "node", nodeName)
+}
+func (leh *LogEventHandle) AddPod(nodeName, podNamespace, podName string) {
+	leh.mutex.Lock()
+	defer leh.mutex.Unlock()
+	leh.infos.AddPod(nodeName, podNamespace, podName)
+	leh.logger.Info("add pod", "node", nodeName, "namespace", podNamespace, "pod", podName)
+}
+func (leh *LogEventHandle) DeletePod(nodeName, podNamespace, podName string) {
+	leh.mutex.Lock()
+	defer leh.mutex.Unlock()
+	leh.infos.DeletePod(nodeName, podNamespace, podName)
+	leh.logger.Info("delete pod", "node", nodeName, "namespace", podNamespace, "pod", podName)
+}
+func (leh *LogEventHandle) ReschedulePod(ev eventsource.RescheduleEvent) {
+	leh.mutex.Lock()
+	defer leh.mutex.Unlock()
+	leh.infos.ReschedulePod(ev)
+	leh.logger.Info("reschedule pod", "event", ev)
+}
+func (leh *LogEventHandle) GetCurNodeInfos() eventsource.Infos {
+	leh.mutex.Lock()
+	defer leh.mutex.Unlock()
+	return leh.infos.Copy()
+}
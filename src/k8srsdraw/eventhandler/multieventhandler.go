@@ -0,0 +1,191 @@
+package eventhandler
+
+import (
+	"fmt"
+	"k8srsdraw/eventsource"
+	"k8srsdraw/workqueue"
+	"sync"
+	"time"
+)
+
+type callWorkItem struct {
+	workqueue.WorkItem
+	id string
+	f  func()
+}
+
+func (wi *callWorkItem) GetID() string {
+	return wi.id
+}
+func (wi *callWorkItem) Run() {
+	wi.f()
+}
+
+type consumer struct {
+	eventHandle eventsource.EventHandle
+	workQueue   *workqueue.WorkQueue
+}
+
+func (c *consumer) call(id string, f func()) {
+	c.workQueue.AsyncRun(&callWorkItem{id: id, f: f})
+}
+
+// MultiEventHandle broadcasts every call to several EventHandles. Each of
+// them gets its own work queue so a slow consumer (the window sleeps while
+// animating) never holds back the others nor the source.
+//
+// GetCurNodeInfos is answered from a copy of the cluster kept up to date as
+// calls come in, not from a consumer which may still be behind.
+type MultiEventHandle struct {
+	mutex     sync.Mutex
+	infos     eventsource.Infos
+	consumers []*consumer
+}
+
+func NewMultiEventHandle(handles ...eventsource.EventHandle) *MultiEventHandle {
+	m := &MultiEventHandle{
+		infos:     make(eventsource.Infos),
+		consumers: make([]*consumer, 0, len(handles)),
+	}
+	for _, h := range handles {
+		m.consumers = append(m.consumers, &consumer{
+			eventHandle: h,
+			workQueue:   workqueue.NewWorQueue(),
+		})
+	}
+	return m
+}
+
+func (m *MultiEventHandle) Init(infos eventsource.Infos) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.infos = infos.Copy()
+	for _, c := range m.consumers {
+		h, infos := c.eventHandle, infos.Copy()
+		c.call("Init", func() { h.Init(infos) })
+	}
+}
+func (m *MultiEventHandle) AddNode(nodeName string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.infos.AddNode(nodeName)
+	for _, c := range m.consumers {
+		h := c.eventHandle
+		c.call("AddNode", func() { h.AddNode(nodeName) })
+	}
+}
+func (m *MultiEventHandle) DeleteNode(nodeName string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.infos.DeleteNode(nodeName)
+	for _, c := range m.consumers {
+		h := c.eventHandle
+		c.call("DeleteNode", func() { h.DeleteNode(nodeName) })
+	}
+}
+func (m *MultiEventHandle) AddPod(nodeName, podNamespace, podName string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.infos.AddPod(nodeName, podNamespace, podName)
+	for _, c := range m.consumers {
+		h := c.eventHandle
+		c.call("AddPod", func() { h.AddPod(nodeName, podNamespace, podName) })
+	}
+}
+func (m *MultiEventHandle) DeletePod(nodeName, podNamespace, podName string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.infos.DeletePod(nodeName, podNamespace, podName)
+	for _, c := range m.consumers {
+		h := c.eventHandle
+		c.call("DeletePod", func() { h.DeletePod(nodeName, podNamespace, podName) })
+	}
+}
+func (m *MultiEventHandle) ReschedulePod(ev eventsource.RescheduleEvent) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.infos.ReschedulePod(ev)
+	for _, c := range m.consumers {
+		h := c.eventHandle
+		c.call("ReschedulePod", func() { h.ReschedulePod(ev) })
+	}
+}
+func (m *MultiEventHandle) GetCurNodeInfos() eventsource.Infos {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.infos.Copy()
+}
+
+// The connection status and rounds are forwarded to the
+// consumers which care about them.
+
+func (m *MultiEventHandle) Connected() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, c := range m.consumers {
+		if h, ok := c.eventHandle.(eventsource.ConnStatusHandle); ok {
+			c.call("Connected", h.Connected)
+		}
+	}
+}
+func (m *MultiEventHandle) Disconnected(err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, c := range m.consumers {
+		if h, ok := c.eventHandle.(eventsource.ConnStatusHandle); ok {
+			c.call("Disconnected", func() { h.Disconnected(err) })
+		}
+	}
+}
+func (m *MultiEventHandle) Alive(t time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, c := range m.consumers {
+		if h, ok := c.eventHandle.(eventsource.ConnStatusHandle); ok {
+			c.call("Alive", func() { h.Alive(t) })
+		}
+	}
+}
+
+func (m *MultiEventHandle) RoundStarted() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, c := range m.consumers {
+		if h, ok := c.eventHandle.(eventsource.RoundHandle); ok {
+			c.call("RoundStarted", h.RoundStarted)
+		}
+	}
+}
+func (m *MultiEventHandle) RoundFinished() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, c := range m.consumers {
+		if h, ok := c.eventHandle.(eventsource.RoundHandle); ok {
+			c.call("RoundFinished", h.RoundFinished)
+		}
+	}
+}
+
+// Close waits for every consumer to handle the calls queued so far, then
+// stops their queues. Later calls only update GetCurNodeInfos.
+func (m *MultiEventHandle) Close() {
+	m.mutex.Lock()
+	consumers := m.consumers
+	m.consumers = nil
+	m.mutex.Unlock()
+	for _, c := range consumers {
+		c.workQueue.Stop()
+	}
+}
+
+// QueueDepths returns how many calls each consumer still has to handle,
+// keyed by the consumer's type.
+func (m *MultiEventHandle) QueueDepths() map[string]int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	ret := make(map[string]int, len(m.consumers))
+	for _, c := range m.consumers {
+		ret[fmt.Sprintf("%T", c.eventHandle)] += c.workQueue.Len()
+	}
+	return ret
+}
@@ -0,0 +1,88 @@
+package eventhandler
+
+import (
+	"encoding/json"
+	"io"
+	"k8srsdraw/eventsource"
+	"k8srsdraw/logging"
+	"sync"
+	"time"
+)
+
+// RecordEventHandle writes every call as an eventsource.Record line, the
+// output can be played back with -source=replay.
+type RecordEventHandle struct {
+	mutex sync.Mutex
+	enc   *json.Encoder
+	infos eventsource.Infos
+}
+
+func NewRecordEventHandle(w io.Writer) *RecordEventHandle {
+	return &RecordEventHandle{
+		enc:   json.NewEncoder(w),
+		infos: make(eventsource.Infos),
+	}
+}
+
+func (reh *RecordEventHandle) write(r *eventsource.Record) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if err := reh.enc.Encode(r); err != nil {
+		logging.For("record").Warn("write record failed", "type", r.Type, "err", err)
+	}
+}
+
+func (reh *RecordEventHandle) Init(infos eventsource.Infos) {
+	reh.mutex.Lock()
+	defer reh.mutex.Unlock()
+	reh.infos = infos.Copy()
+	reh.write(&eventsource.Record{Type: eventsource.RECORD_INIT, Infos: infos})
+}
+func (reh *RecordEventHandle) AddNode(nodeName string) {
+	reh.mutex.Lock()
+	defer reh.mutex.Unlock()
+	reh.infos.AddNode(nodeName)
+	reh.write(&eventsource.Record{Type: eventsource.RECORD_ADDNODE, NodeName: nodeName})
+}
+func (reh *RecordEventHandle) DeleteNode(nodeName string) {
+	reh.mutex.Lock()
+	defer reh.mutex.Unlock()
+	reh.infos.DeleteNode(nodeName)
+	reh.write(&eventsource.Record{Type: eventsource.RECORD_DELETENODE, NodeName: nodeName})
+}
+func (reh *RecordEventHandle) AddPod(nodeName, podNamespace, podName string) {
+	reh.mutex.Lock()
+	defer reh.mutex.Unlock()
+	reh.infos.AddPod(nodeName, podNamespace, podName)
+	reh.write(&eventsource.Record{Type: eventsource.RECORD_ADDPOD,
+		NodeName: nodeName, Namespace: podNamespace, PodName: podName})
+}
+func (reh *RecordEventHandle) DeletePod(nodeName, podNamespace, podName string) {
+	reh.mutex.Lock()
+	defer reh.mutex.Unlock()
+	reh.infos.DeletePod(nodeName, podNamespace, podName)
+	reh.write(&eventsource.Record{Type: eventsource.RECORD_DELETEPOD,
+		NodeName: nodeName, Namespace: podNamespace, PodName: podName})
+}
+func (reh *RecordEventHandle) ReschedulePod(ev eventsource.RescheduleEvent) {
+	reh.mutex.Lock()
+	defer reh.mutex.Unlock()
+	reh.infos.ReschedulePod(ev)
+	reh.write(eventsource.NewRescheduleRecord(ev))
+}
+func (reh *RecordEventHandle) RoundStarted() {
+	reh.mutex.Lock()
+	defer reh.mutex.Unlock()
+	reh.write(&eventsource.Record{Type: eventsource.RECORD_ROUNDSTART})
+}
+func (reh *RecordEventHandle) RoundFinished() {
+	reh.mutex.Lock()
+	defer reh.mutex.Unlock()
+	reh.write(&eventsource.Record{Type: eventsource.RECORD_ROUNDSTOP})
+}
+func (reh *RecordEventHandle) GetCurNodeInfos() eventsource.Infos {
+	reh.mutex.Lock()
+	defer reh.mutex.Unlock()
+	return reh.infos.Copy()
+}
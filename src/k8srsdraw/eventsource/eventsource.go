@@ -0,0 +1,89 @@
+// Package eventsource defines the cluster events shared by every producer
+// (rescheduler socket, kubernetes watch, replay file, mock generator) and
+// every consumer (the window and friends).
+package eventsource
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type PodInfos struct {
+	Name      string
+	Namespace string
+}
+
+type NodeInfos struct {
+	NodeName string
+	PodInfos []PodInfos
+}
+
+type Infos map[string]*NodeInfos
+
+// RescheduleEvent is the outcome of the rescheduler moving one pod: the pod
+// FromPodName on FromNodeName is replaced by ToPodName on ToNodeName. When
+// Success is false nothing moved.
+type RescheduleEvent struct {
+	Namespace    string    `json:"namespace"`
+	FromPodName  string    `json:"fromPod"`
+	FromNodeName string    `json:"fromNode"`
+	ToPodName    string    `json:"toPod"`
+	ToNodeName   string    `json:"toNode"`
+	Time         time.Time `json:"time"`
+	// Reason is why the pod was moved, or why it could not be, when the
+	// source knows.
+	Reason  string `json:"reason,omitempty"`
+	Success bool   `json:"success"`
+}
+
+// LogValue makes every logger print an event the same way.
+func (ev RescheduleEvent) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("namespace", ev.Namespace),
+		slog.String("fromPod", ev.FromPodName),
+		slog.String("fromNode", ev.FromNodeName),
+		slog.String("toPod", ev.ToPodName),
+		slog.String("toNode", ev.ToNodeName),
+		slog.Bool("success", ev.Success),
+	}
+	if ev.Reason != "" {
+		attrs = append(attrs, slog.String("reason", ev.Reason))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+type EventHandle interface {
+	Init(infos Infos)
+	AddNode(nodeName string)
+	DeleteNode(nodeName string)
+	AddPod(nodeName, podNamespace, podName string)
+	DeletePod(nodeName, podNamespace, podName string)
+	// ReschedulePod is called for failed reschedules too, see
+	// RescheduleEvent.Success.
+	ReschedulePod(ev RescheduleEvent)
+	GetCurNodeInfos() Infos
+}
+
+// ConnStatusHandle is optionally implemented by an EventHandle which wants
+// to follow the health of the connection.
+type ConnStatusHandle interface {
+	Connected()
+	Disconnected(err error)
+	// Alive is called whenever data arrived from the server, heartbeats
+	// included.
+	Alive(t time.Time)
+}
+
+// RoundHandle is optionally implemented by an EventHandle which wants to
+// know when the rescheduler starts and ends a round of reschedules.
+type RoundHandle interface {
+	RoundStarted()
+	RoundFinished()
+}
+
+// EventSource produces EventHandle calls until ctx is done or the source
+// fails for good.
+type EventSource interface {
+	Run(ctx context.Context, eventHandle EventHandle) error
+}
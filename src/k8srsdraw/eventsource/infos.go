@@ -0,0 +1,69 @@
+package eventsource
+
+// The helpers below let a consumer keep its own copy of the cluster up to
+// date from EventHandle calls, with the same semantics as the window: pods
+// of unknown nodes are dropped and adding an existing pod is a no-op.
+
+// Copy returns a deep copy of infos.
+func (infos Infos) Copy() Infos {
+	ret := make(Infos)
+	for name, info := range infos {
+		if info == nil {
+			continue
+		}
+		ret[name] = &NodeInfos{NodeName: info.NodeName, PodInfos: append([]PodInfos(nil), info.PodInfos...)}
+	}
+	return ret
+}
+
+func (infos Infos) AddNode(nodeName string) {
+	if _, ok := infos[nodeName]; !ok {
+		infos[nodeName] = &NodeInfos{NodeName: nodeName, PodInfos: make([]PodInfos, 0)}
+	}
+}
+
+func (infos Infos) DeleteNode(nodeName string) {
+	delete(infos, nodeName)
+}
+
+func (infos Infos) AddPod(nodeName, podNamespace, podName string) {
+	info, ok := infos[nodeName]
+	if !ok {
+		return
+	}
+	for _, p := range info.PodInfos {
+		if p.Namespace == podNamespace && p.Name == podName {
+			return
+		}
+	}
+	info.PodInfos = append(info.PodInfos, PodInfos{Name: podName, Namespace: podNamespace})
+}
+
+func (infos Infos) DeletePod(nodeName, podNamespace, podName string) {
+	info, ok := infos[nodeName]
+	if !ok {
+		return
+	}
+	for i, p := range info.PodInfos {
+		if p.Namespace == podNamespace && p.Name == podName {
+			info.PodInfos = append(info.PodInfos[:i:i], info.PodInfos[i+1:]...)
+			return
+		}
+	}
+}
+
+// ReschedulePod moves a pod the way the window does: nothing happens unless
+// the reschedule succeeded and both nodes are known.
+func (infos Infos) ReschedulePod(ev RescheduleEvent) {
+	if !ev.Success {
+		return
+	}
+	if _, ok := infos[ev.FromNodeName]; !ok {
+		return
+	}
+	if _, ok := infos[ev.ToNodeName]; !ok {
+		return
+	}
+	infos.DeletePod(ev.FromNodeName, ev.Namespace, ev.FromPodName)
+	infos.AddPod(ev.ToNodeName, ev.Namespace, ev.ToPodName)
+}
@@ -0,0 +1,136 @@
+package eventsource
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// FakeCluster is a random node -> pods distribution which can be
+// rescheduled one pod at a time.
+type FakeCluster struct {
+	infos Infos
+	rnd   *rand.Rand
+}
+
+func NewFakeCluster(nodeNum, nsNum, podNum int) *FakeCluster {
+	c := &FakeCluster{
+		infos: make(Infos),
+		rnd:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	nodes := make([]string, 0, nodeNum)
+	for i := 0; i < nodeNum; i++ {
+		name := fmt.Sprintf("node%d", i)
+		nodes = append(nodes, name)
+		c.infos[name] = &NodeInfos{NodeName: name, PodInfos: make([]PodInfos, 0)}
+	}
+	if len(nodes) == 0 {
+		return c
+	}
+	for n := 0; n < nsNum; n++ {
+		ns := fmt.Sprintf("namespace%d", n)
+		for p := 0; p < podNum; p++ {
+			node := c.infos[nodes[c.rnd.Intn(len(nodes))]]
+			node.PodInfos = append(node.PodInfos, PodInfos{Name: c.podName(ns), Namespace: ns})
+		}
+	}
+	return c
+}
+
+func (c *FakeCluster) podName(ns string) string {
+	return fmt.Sprintf("%s-%05x", ns, c.rnd.Intn(0xfffff))
+}
+
+// Infos returns a copy of the current distribution.
+func (c *FakeCluster) Infos() Infos {
+	return c.infos.Copy()
+}
+
+// Reschedule replaces one random pod by a new pod of the same namespace on
+// another node.
+func (c *FakeCluster) Reschedule() (RescheduleEvent, bool) {
+	if len(c.infos) < 2 {
+		return RescheduleEvent{}, false
+	}
+	names := make([]string, 0, len(c.infos))
+	for name, info := range c.infos {
+		if len(info.PodInfos) > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return RescheduleEvent{}, false
+	}
+	from := c.infos[names[c.rnd.Intn(len(names))]]
+	others := make([]*NodeInfos, 0, len(c.infos)-1)
+	for _, info := range c.infos {
+		if info != from {
+			others = append(others, info)
+		}
+	}
+	to := others[c.rnd.Intn(len(others))]
+	i := c.rnd.Intn(len(from.PodInfos))
+	pod := from.PodInfos[i]
+	from.PodInfos = append(from.PodInfos[:i], from.PodInfos[i+1:]...)
+	newPod := PodInfos{Name: c.podName(pod.Namespace), Namespace: pod.Namespace}
+	to.PodInfos = append(to.PodInfos, newPod)
+	return RescheduleEvent{
+		Namespace:    pod.Namespace,
+		FromPodName:  pod.Name,
+		FromNodeName: from.NodeName,
+		ToPodName:    newPod.Name,
+		ToNodeName:   to.NodeName,
+		Time:         time.Now(),
+		Reason:       "random move",
+		Success:      true,
+	}, true
+}
+
+// MockSource drives a FakeCluster in process, no server needed.
+type MockSource struct {
+	nodeNum  int
+	nsNum    int
+	podNum   int
+	interval time.Duration
+}
+
+// DefaultMockInterval is the time between two reschedules of a MockSource
+// created without a positive interval.
+var DefaultMockInterval = 3 * time.Second
+
+func NewMockSource(nodeNum, nsNum, podNum int, interval time.Duration) *MockSource {
+	if interval <= 0 {
+		interval = DefaultMockInterval
+	}
+	return &MockSource{
+		nodeNum:  nodeNum,
+		nsNum:    nsNum,
+		podNum:   podNum,
+		interval: interval,
+	}
+}
+
+func (ms *MockSource) Run(ctx context.Context, eventHandle EventHandle) error {
+	c := NewFakeCluster(ms.nodeNum, ms.nsNum, ms.podNum)
+	eventHandle.Init(c.Infos())
+	ticker := time.NewTicker(ms.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		rh, _ := eventHandle.(RoundHandle)
+		if rh != nil {
+			rh.RoundStarted()
+		}
+		if ev, ok := c.Reschedule(); ok {
+			eventHandle.ReschedulePod(ev)
+		}
+		if rh != nil {
+			rh.RoundFinished()
+		}
+	}
+}
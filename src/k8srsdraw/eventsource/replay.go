@@ -0,0 +1,154 @@
+package eventsource
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	RECORD_INIT           string = "init"
+	RECORD_ADDNODE        string = "addNode"
+	RECORD_DELETENODE     string = "deleteNode"
+	RECORD_ADDPOD         string = "addPod"
+	RECORD_DELETEPOD      string = "deletePod"
+	RECORD_RESCHEDULEPOD  string = "reschedulePod"
+	RECORD_RESCHEDULEFAIL string = "rescheduleFailed"
+	RECORD_ROUNDSTART     string = "roundStart"
+	RECORD_ROUNDSTOP      string = "roundStop"
+)
+
+// Record is one EventHandle call. A replay file holds one JSON Record per
+// line. For reschedules NodeName and PodName describe the source pod, and
+// the type tells whether it succeeded.
+type Record struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	Infos      Infos     `json:"infos,omitempty"`
+	NodeName   string    `json:"node,omitempty"`
+	Namespace  string    `json:"namespace,omitempty"`
+	PodName    string    `json:"pod,omitempty"`
+	ToNodeName string    `json:"toNode,omitempty"`
+	ToPodName  string    `json:"toPod,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// NewRescheduleRecord returns the record of ev, stamped with the time of ev.
+func NewRescheduleRecord(ev RescheduleEvent) *Record {
+	r := &Record{
+		Time:       ev.Time,
+		Type:       RECORD_RESCHEDULEPOD,
+		NodeName:   ev.FromNodeName,
+		Namespace:  ev.Namespace,
+		PodName:    ev.FromPodName,
+		ToNodeName: ev.ToNodeName,
+		ToPodName:  ev.ToPodName,
+		Reason:     ev.Reason,
+	}
+	if !ev.Success {
+		r.Type = RECORD_RESCHEDULEFAIL
+	}
+	return r
+}
+
+// RescheduleEvent returns the event a reschedule record stands for.
+func (r *Record) RescheduleEvent() RescheduleEvent {
+	return RescheduleEvent{
+		Namespace:    r.Namespace,
+		FromPodName:  r.PodName,
+		FromNodeName: r.NodeName,
+		ToPodName:    r.ToPodName,
+		ToNodeName:   r.ToNodeName,
+		Time:         r.Time,
+		Reason:       r.Reason,
+		Success:      r.Type == RECORD_RESCHEDULEPOD,
+	}
+}
+
+// Apply makes the EventHandle call the record stands for.
+func (r *Record) Apply(eventHandle EventHandle) error {
+	switch r.Type {
+	case RECORD_INIT:
+		eventHandle.Init(r.Infos)
+	case RECORD_ADDNODE:
+		eventHandle.AddNode(r.NodeName)
+	case RECORD_DELETENODE:
+		eventHandle.DeleteNode(r.NodeName)
+	case RECORD_ADDPOD:
+		eventHandle.AddPod(r.NodeName, r.Namespace, r.PodName)
+	case RECORD_DELETEPOD:
+		eventHandle.DeletePod(r.NodeName, r.Namespace, r.PodName)
+	case RECORD_RESCHEDULEPOD, RECORD_RESCHEDULEFAIL:
+		eventHandle.ReschedulePod(r.RescheduleEvent())
+	case RECORD_ROUNDSTART:
+		if h, ok := eventHandle.(RoundHandle); ok {
+			h.RoundStarted()
+		}
+	case RECORD_ROUNDSTOP:
+		if h, ok := eventHandle.(RoundHandle); ok {
+			h.RoundFinished()
+		}
+	default:
+		return fmt.Errorf("unknown record type %q", r.Type)
+	}
+	return nil
+}
+
+// ReplaySource plays back a file of Records.
+type ReplaySource struct {
+	path string
+	// speed scales the recorded delays, 2 plays twice as fast and 0 plays
+	// everything at once.
+	speed float64
+}
+
+func NewReplaySource(path string, speed float64) *ReplaySource {
+	return &ReplaySource{
+		path:  path,
+		speed: speed,
+	}
+}
+
+func (rs *ReplaySource) Run(ctx context.Context, eventHandle EventHandle) error {
+	f, err := os.Open(rs.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// init records carry the whole cluster on one line
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	var last time.Time
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		r := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			return fmt.Errorf("%s:%d: %v", rs.path, line, err)
+		}
+		if rs.speed > 0 && !last.IsZero() && r.Time.After(last) {
+			select {
+			case <-time.After(time.Duration(float64(r.Time.Sub(last)) / rs.speed)):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		last = r.Time
+		if err := r.Apply(eventHandle); err != nil {
+			return fmt.Errorf("%s:%d: %v", rs.path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	// keep the replayed state on screen until we are told to stop
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,342 @@
+// Package kubesource produces the same EventHandle calls as the rescheduler
+// socket by watching Nodes and Pods of a cluster directly.
+package kubesource
+
+import (
+	"context"
+	"fmt"
+	"k8srsdraw/eventsource"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Options tunes a Source, zero values pick the defaults.
+type Options struct {
+	// Resync is the informer resync period.
+	Resync time.Duration
+	// RescheduleWindow is how long a deleted pod waits for a replacement
+	// from the same owner before it is reported as a plain deletion.
+	RescheduleWindow time.Duration
+}
+
+type podState struct {
+	namespace string
+	name      string
+	nodeName  string
+	owner     string
+}
+
+// deletedPod is a pod which is gone but may still be matched with its
+// replacement to report a reschedule.
+type deletedPod struct {
+	pod   podState
+	timer *time.Timer
+}
+
+// Source lists and watches Nodes and Pods and infers reschedules from pods
+// which are deleted and then recreated by the same owner on another node.
+type Source struct {
+	client      kubernetes.Interface
+	eventHandle eventsource.EventHandle
+	opts        Options
+	mutex       sync.Mutex
+	initialized bool
+	nodes       map[string]bool
+	pods        map[string]podState
+	pending     map[string][]*deletedPod
+}
+
+func NewSource(client kubernetes.Interface, opts Options) *Source {
+	if opts.RescheduleWindow <= 0 {
+		opts.RescheduleWindow = 30 * time.Second
+	}
+	return &Source{
+		client:  client,
+		opts:    opts,
+		nodes:   make(map[string]bool),
+		pods:    make(map[string]podState),
+		pending: make(map[string][]*deletedPod),
+	}
+}
+
+// NewSourceFromKubeconfig builds a Source for the cluster of a kubeconfig
+// file, an empty path uses the in-cluster configuration.
+func NewSourceFromKubeconfig(kubeconfig string, opts Options) (*Source, error) {
+	cfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %v", err)
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("create client: %v", err)
+	}
+	return NewSource(client, opts), nil
+}
+
+// Run watches the cluster until ctx is done. The initial list is handed to
+// EventHandle.Init once both caches are synced, later changes are
+// forwarded one by one.
+func (s *Source) Run(ctx context.Context, eventHandle eventsource.EventHandle) error {
+	s.mutex.Lock()
+	s.eventHandle = eventHandle
+	s.initialized = false
+	s.nodes = make(map[string]bool)
+	s.pods = make(map[string]podState)
+	s.mutex.Unlock()
+
+	stopCh := ctx.Done()
+	factory := informers.NewSharedInformerFactory(s.client, s.opts.Resync)
+	nodeInformer := factory.Core().V1().Nodes().Informer()
+	podInformer := factory.Core().V1().Pods().Informer()
+
+	nodeReg, err := nodeInformer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if node, ok := obj.(*corev1.Node); ok {
+				s.addNode(node.Name)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if node, ok := tombstoned(obj).(*corev1.Node); ok {
+				s.deleteNode(node.Name)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+	podReg, err := podInformer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				s.updatePod(pod)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if pod, ok := newObj.(*corev1.Pod); ok {
+				s.updatePod(pod)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if pod, ok := tombstoned(obj).(*corev1.Pod); ok {
+				s.deletePod(podKey(pod.Namespace, pod.Name))
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	factory.Start(stopCh)
+	defer factory.Shutdown()
+	if !cache.WaitForCacheSync(stopCh, nodeReg.HasSynced, podReg.HasSynced) {
+		return fmt.Errorf("caches not synced")
+	}
+	s.init()
+	<-stopCh
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.initialized = false
+	for key, list := range s.pending {
+		for _, dp := range list {
+			dp.timer.Stop()
+		}
+		delete(s.pending, key)
+	}
+	return nil
+}
+
+func (s *Source) init() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	infos := make(eventsource.Infos)
+	for name := range s.nodes {
+		infos[name] = &eventsource.NodeInfos{NodeName: name, PodInfos: make([]eventsource.PodInfos, 0)}
+	}
+	for _, p := range s.pods {
+		if nodeInfo, ok := infos[p.nodeName]; ok {
+			nodeInfo.PodInfos = append(nodeInfo.PodInfos, eventsource.PodInfos{Name: p.name, Namespace: p.namespace})
+		}
+	}
+	s.eventHandle.Init(infos)
+	s.initialized = true
+}
+
+func (s *Source) addNode(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.nodes[name] {
+		return
+	}
+	s.nodes[name] = true
+	if s.initialized {
+		s.eventHandle.AddNode(name)
+	}
+}
+
+func (s *Source) deleteNode(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.nodes[name] {
+		return
+	}
+	delete(s.nodes, name)
+	if s.initialized {
+		s.eventHandle.DeleteNode(name)
+	}
+}
+
+// updatePod handles both added and updated pods. Pods only count once they
+// are bound to a node and until they terminate: a pod being deleted is gone
+// already, its replacement is usually bound before it is removed.
+func (s *Source) updatePod(pod *corev1.Pod) {
+	key := podKey(pod.Namespace, pod.Name)
+	if pod.Spec.NodeName == "" {
+		return
+	}
+	if pod.DeletionTimestamp != nil ||
+		pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		s.deletePod(key)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if old, ok := s.pods[key]; ok {
+		if old.nodeName == pod.Spec.NodeName {
+			return
+		}
+		// a bound pod never moves, but be safe if we missed a delete
+		s.removePodLocked(key, false)
+	}
+	p := podState{
+		namespace: pod.Namespace,
+		name:      pod.Name,
+		nodeName:  pod.Spec.NodeName,
+		owner:     ownerKey(pod),
+	}
+	s.pods[key] = p
+	if !s.initialized {
+		return
+	}
+	if from := s.takePendingLocked(p.owner, p.nodeName); from != nil {
+		s.eventHandle.ReschedulePod(eventsource.RescheduleEvent{
+			Namespace:    p.namespace,
+			FromPodName:  from.name,
+			FromNodeName: from.nodeName,
+			ToPodName:    p.name,
+			ToNodeName:   p.nodeName,
+			Time:         time.Now(),
+			Reason:       "replaced by its owner on another node",
+			Success:      true,
+		})
+		return
+	}
+	s.eventHandle.AddPod(p.nodeName, p.namespace, p.name)
+}
+
+func (s *Source) deletePod(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.removePodLocked(key, true)
+}
+
+// removePodLocked forgets a pod. When canReschedule is set and the pod has
+// an owner, reporting the deletion is delayed in case a replacement shows up
+// on another node.
+func (s *Source) removePodLocked(key string, canReschedule bool) {
+	p, ok := s.pods[key]
+	if !ok {
+		return
+	}
+	delete(s.pods, key)
+	if !s.initialized {
+		return
+	}
+	if !canReschedule || p.owner == "" {
+		s.eventHandle.DeletePod(p.nodeName, p.namespace, p.name)
+		return
+	}
+	dp := &deletedPod{pod: p}
+	dp.timer = time.AfterFunc(s.opts.RescheduleWindow, func() {
+		s.expire(dp)
+	})
+	s.pending[p.owner] = append(s.pending[p.owner], dp)
+}
+
+// takePendingLocked pops the oldest pending pod of owner deleted from
+// another node than nodeName and returns it. A replacement on the same node
+// is not a reschedule: when no pod of another node is pending, the oldest
+// one of nodeName is reported deleted right away and nil is returned.
+func (s *Source) takePendingLocked(owner, nodeName string) *podState {
+	list := s.pending[owner]
+	if len(list) == 0 {
+		return nil
+	}
+	i := 0
+	for i < len(list) && list[i].pod.nodeName == nodeName {
+		i++
+	}
+	if i == len(list) {
+		i = 0
+	}
+	dp := list[i]
+	dp.timer.Stop()
+	s.pending[owner] = append(list[:i:i], list[i+1:]...)
+	if len(s.pending[owner]) == 0 {
+		delete(s.pending, owner)
+	}
+	if dp.pod.nodeName == nodeName {
+		s.eventHandle.DeletePod(dp.pod.nodeName, dp.pod.namespace, dp.pod.name)
+		return nil
+	}
+	return &dp.pod
+}
+
+// expire reports dp deleted once nothing replaced it. Stopping a timer does
+// not stop a callback already running, so it checks the source still runs.
+func (s *Source) expire(dp *deletedPod) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.initialized {
+		return
+	}
+	list := s.pending[dp.pod.owner]
+	for i, item := range list {
+		if item == dp {
+			s.pending[dp.pod.owner] = append(list[:i:i], list[i+1:]...)
+			if len(s.pending[dp.pod.owner]) == 0 {
+				delete(s.pending, dp.pod.owner)
+			}
+			s.eventHandle.DeletePod(dp.pod.nodeName, dp.pod.namespace, dp.pod.name)
+			return
+		}
+	}
+}
+
+func podKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// ownerKey identifies the controller of a pod, pods without one can not be
+// matched with a replacement.
+func ownerKey(pod *corev1.Pod) string {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return ""
+	}
+	return pod.Namespace + "/" + string(ref.UID)
+}
+
+func tombstoned(obj interface{}) interface{} {
+	if t, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return t.Obj
+	}
+	return obj
+}
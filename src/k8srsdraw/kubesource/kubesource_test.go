@@ -0,0 +1,242 @@
+package kubesource
+
+import (
+	"context"
+	"fmt"
+	"k8srsdraw/eventsource"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// recorder is an EventHandle writing every call down as a string.
+type recorder struct {
+	mutex sync.Mutex
+	calls []string
+	next  int
+}
+
+func (r *recorder) add(format string, a ...interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.calls = append(r.calls, fmt.Sprintf(format, a...))
+}
+func (r *recorder) Init(infos eventsource.Infos) {
+	nodes := make([]string, 0)
+	for name, info := range infos {
+		pods := make([]string, 0)
+		for _, p := range info.PodInfos {
+			pods = append(pods, p.Namespace+"/"+p.Name)
+		}
+		sort.Strings(pods)
+		nodes = append(nodes, name+"["+strings.Join(pods, " ")+"]")
+	}
+	sort.Strings(nodes)
+	r.add("Init %s", strings.Join(nodes, " "))
+}
+func (r *recorder) AddNode(nodeName string) {
+	r.add("AddNode %s", nodeName)
+}
+func (r *recorder) DeleteNode(nodeName string) {
+	r.add("DeleteNode %s", nodeName)
+}
+func (r *recorder) AddPod(nodeName, podNamespace, podName string) {
+	r.add("AddPod %s %s/%s", nodeName, podNamespace, podName)
+}
+func (r *recorder) DeletePod(nodeName, podNamespace, podName string) {
+	r.add("DeletePod %s %s/%s", nodeName, podNamespace, podName)
+}
+func (r *recorder) ReschedulePod(ev eventsource.RescheduleEvent) {
+	r.add("ReschedulePod %s/%s %s -> %s/%s %s", ev.Namespace, ev.FromPodName, ev.FromNodeName,
+		ev.Namespace, ev.ToPodName, ev.ToNodeName)
+}
+func (r *recorder) GetCurNodeInfos() eventsource.Infos {
+	return nil
+}
+
+// expect waits for the next calls to be want.
+func (r *recorder) expect(t *testing.T, want ...string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mutex.Lock()
+		got := r.calls[r.next:]
+		if len(got) >= len(want) {
+			got = got[:len(want)]
+			r.next += len(want)
+			r.mutex.Unlock()
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("got calls %q, want %q", got, want)
+				}
+			}
+			return
+		}
+		r.mutex.Unlock()
+		if time.Now().After(deadline) {
+			t.Fatalf("got calls %q, want %q", got, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// expectNothing checks no call arrives for a while.
+func (r *recorder) expectNothing(t *testing.T, d time.Duration) {
+	t.Helper()
+	time.Sleep(d)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if got := r.calls[r.next:]; len(got) > 0 {
+		t.Fatalf("got unexpected calls %q", got)
+	}
+}
+
+func node(name string) *corev1.Node {
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+// pod returns a running pod of ns on nodeName, controlled by owner unless
+// it is empty.
+func pod(ns, name, nodeName, owner string) *corev1.Pod {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if owner != "" {
+		controller := true
+		p.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "apps/v1",
+			Kind:       "ReplicaSet",
+			Name:       owner,
+			UID:        types.UID(owner),
+			Controller: &controller,
+		}}
+	}
+	return p
+}
+
+// start runs a Source on a fake clientset holding objects and waits for
+// its Init.
+func start(t *testing.T, window time.Duration, objects ...runtime.Object) (kubernetes.Interface, *recorder) {
+	t.Helper()
+	client := fake.NewClientset(objects...)
+	s := NewSource(client, Options{RescheduleWindow: window})
+	r := &recorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx, r)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+	return client, r
+}
+
+func TestInit(t *testing.T) {
+	_, r := start(t, time.Minute,
+		node("n1"), node("n2"),
+		pod("default", "a", "n1", "rs"),
+		pod("default", "b", "n2", ""),
+		pod("default", "pending", "", "rs"))
+	r.expect(t, "Init n1[default/a] n2[default/b]")
+}
+
+func TestNodesAndPods(t *testing.T) {
+	client, r := start(t, time.Minute, node("n1"))
+	r.expect(t, "Init n1[]")
+	ctx := context.Background()
+
+	if _, err := client.CoreV1().Nodes().Create(ctx, node("n2"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(t, "AddNode n2")
+	if _, err := client.CoreV1().Pods("default").Create(ctx, pod("default", "a", "n2", ""), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(t, "AddPod n2 default/a")
+	// a pod without an owner can not be replaced, its deletion is
+	// reported at once
+	if err := client.CoreV1().Pods("default").Delete(ctx, "a", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(t, "DeletePod n2 default/a")
+	if err := client.CoreV1().Nodes().Delete(ctx, "n2", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(t, "DeleteNode n2")
+}
+
+func TestReschedule(t *testing.T) {
+	client, r := start(t, time.Minute, node("n1"), node("n2"), pod("default", "a", "n1", "rs"))
+	r.expect(t, "Init n1[default/a] n2[]")
+	ctx := context.Background()
+
+	if err := client.CoreV1().Pods("default").Delete(ctx, "a", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CoreV1().Pods("default").Create(ctx, pod("default", "b", "n2", "rs"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(t, "ReschedulePod default/a n1 -> default/b n2")
+}
+
+// A controller binds the replacement while the old pod still terminates.
+func TestRescheduleWhileTerminating(t *testing.T) {
+	client, r := start(t, time.Minute, node("n1"), node("n2"), pod("default", "a", "n1", "rs"))
+	r.expect(t, "Init n1[default/a] n2[]")
+	ctx := context.Background()
+
+	old := pod("default", "a", "n1", "rs")
+	now := metav1.Now()
+	old.DeletionTimestamp = &now
+	if _, err := client.CoreV1().Pods("default").Update(ctx, old, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CoreV1().Pods("default").Create(ctx, pod("default", "b", "n2", "rs"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(t, "ReschedulePod default/a n1 -> default/b n2")
+	// the final deletion of the old pod is not reported again
+	if err := client.CoreV1().Pods("default").Delete(ctx, "a", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	r.expectNothing(t, 100*time.Millisecond)
+}
+
+func TestSameNodeReplacement(t *testing.T) {
+	client, r := start(t, time.Minute, node("n1"), node("n2"), pod("default", "a", "n1", "rs"))
+	r.expect(t, "Init n1[default/a] n2[]")
+	ctx := context.Background()
+
+	if err := client.CoreV1().Pods("default").Delete(ctx, "a", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CoreV1().Pods("default").Create(ctx, pod("default", "b", "n1", "rs"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(t, "DeletePod n1 default/a", "AddPod n1 default/b")
+}
+
+func TestWindowExpiry(t *testing.T) {
+	client, r := start(t, 50*time.Millisecond, node("n1"), pod("default", "a", "n1", "rs"))
+	r.expect(t, "Init n1[default/a]")
+
+	if err := client.CoreV1().Pods("default").Delete(context.Background(), "a", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(t, "DeletePod n1 default/a")
+}
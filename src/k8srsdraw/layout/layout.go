@@ -0,0 +1,82 @@
+// Package layout holds the placement rules shared by every frontend: how
+// nodes are laid out in a grid and how pods are grouped inside a node.
+package layout
+
+import (
+	"k8srsdraw/eventsource"
+	"sort"
+)
+
+// GetRowColum returns the grid used to show num nodes.
+func GetRowColum(num int) (r, c int) {
+	if num <= 4 {
+		return 1, num
+	} else if num <= 8 {
+		return 2, 4
+	} else if num <= 12 {
+		return 3, 4
+	} else if num <= 16 {
+		return 4, 4
+	} else if num <= 20 {
+		return 4, 5
+	} else if num <= 25 {
+		return 5, 5
+	} else if num <= 30 {
+		return 5, 6
+	} else {
+		if num%6 == 0 {
+			return 6, num / 6
+		} else {
+			return 6, num/6 + 1
+		}
+	}
+}
+
+// GetNextPos walks the grid row by row.
+func GetNextPos(rNum, cNum int, curR, curC int) (r, c int) {
+	if curC+1 < cNum {
+		return curR, curC + 1
+	} else if curR+1 < rNum {
+		return curR + 1, 0
+	} else {
+		return rNum - 1, cNum - 1
+	}
+}
+
+// GroupLess orders the namespace groups of a node: biggest first, then by
+// namespace.
+func GroupLess(countI int, nsI string, countJ int, nsJ string) bool {
+	if countI != countJ {
+		return countI > countJ
+	}
+	return nsI < nsJ
+}
+
+// Group is the pods of one namespace on one node.
+type Group struct {
+	Namespace string
+	Names     []string
+}
+
+// GroupPods groups the pods of a node by namespace, ordered by GroupLess.
+// Names inside a group are sorted.
+func GroupPods(pods []eventsource.PodInfos) []Group {
+	byNs := make(map[string]*Group)
+	ret := make([]Group, 0)
+	for _, p := range pods {
+		g, ok := byNs[p.Namespace]
+		if !ok {
+			g = &Group{Namespace: p.Namespace}
+			byNs[p.Namespace] = g
+		}
+		g.Names = append(g.Names, p.Name)
+	}
+	for _, g := range byNs {
+		sort.Strings(g.Names)
+		ret = append(ret, *g)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return GroupLess(len(ret[i].Names), ret[i].Namespace, len(ret[j].Names), ret[j].Namespace)
+	})
+	return ret
+}
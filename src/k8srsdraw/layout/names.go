@@ -0,0 +1,144 @@
+package layout
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NameOrder is how the pod names of a group are listed.
+type NameOrder int
+
+const (
+	// OrderByName lists names alphabetically.
+	OrderByName NameOrder = iota
+	// OrderByAge lists names oldest first, in the order they were added.
+	OrderByAge
+	// OrderByMoved lists the pods rescheduled to the group first, most
+	// recently moved first, then the others by name.
+	OrderByMoved
+)
+
+var nameOrders = map[string]NameOrder{
+	"name":  OrderByName,
+	"age":   OrderByAge,
+	"moved": OrderByMoved,
+}
+
+// ParseNameOrder parses "name", "age" or "moved".
+func ParseNameOrder(s string) (NameOrder, error) {
+	o, ok := nameOrders[s]
+	if !ok {
+		return OrderByName, fmt.Errorf("unknown name order %q, want name, age or moved", s)
+	}
+	return o, nil
+}
+
+type nameEntry struct {
+	added uint64
+	// moved is when the pod was rescheduled here, 0 if it was not
+	moved uint64
+}
+
+// Names is the set of pod names of a group. It remembers in which order
+// names were added and moved in, so that they can be listed in a stable
+// order.
+type Names struct {
+	entries map[string]nameEntry
+	seq     uint64
+}
+
+func NewNames() *Names {
+	return &Names{entries: make(map[string]nameEntry)}
+}
+
+// Add returns false when name was in the set already.
+func (n *Names) Add(name string) bool {
+	return n.add(name, false)
+}
+
+// AddMoved adds name as a pod rescheduled to the group, it returns false
+// when name was in the set already.
+func (n *Names) AddMoved(name string) bool {
+	return n.add(name, true)
+}
+func (n *Names) add(name string, moved bool) bool {
+	if _, ok := n.entries[name]; ok {
+		return false
+	}
+	n.seq++
+	e := nameEntry{added: n.seq}
+	if moved {
+		e.moved = n.seq
+	}
+	n.entries[name] = e
+	return true
+}
+
+// Delete returns false when name was not in the set.
+func (n *Names) Delete(name string) bool {
+	if _, ok := n.entries[name]; !ok {
+		return false
+	}
+	delete(n.entries, name)
+	return true
+}
+func (n *Names) Has(name string) bool {
+	_, ok := n.entries[name]
+	return ok
+}
+func (n *Names) Len() int {
+	return len(n.entries)
+}
+
+// List returns the names in order o.
+func (n *Names) List(o NameOrder) []string {
+	ret := make([]string, 0, len(n.entries))
+	for name := range n.entries {
+		ret = append(ret, name)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		ei, ej := n.entries[ret[i]], n.entries[ret[j]]
+		switch o {
+		case OrderByAge:
+			return ei.added < ej.added
+		case OrderByMoved:
+			if ei.moved != ej.moved {
+				return ei.moved > ej.moved
+			}
+		}
+		return ret[i] < ret[j]
+	})
+	return ret
+}
+
+// Abbreviate shortens names sharing the prefix up to their last dash,
+// like the pods of a ReplicaSet, to that prefix followed by their
+// suffixes in braces: nginx-7d9f8-{abcde,fghij}. Each prefix takes the
+// place of its first name, the order is kept otherwise.
+func Abbreviate(names []string) []string {
+	prefix := func(name string) string {
+		if i := strings.LastIndexByte(name, '-'); i > 0 && i < len(name)-1 {
+			return name[:i+1]
+		}
+		return ""
+	}
+	suffixes := make(map[string][]string)
+	for _, name := range names {
+		if p := prefix(name); p != "" {
+			suffixes[p] = append(suffixes[p], name[len(p):])
+		}
+	}
+	ret := make([]string, 0, len(names))
+	done := make(map[string]bool)
+	for _, name := range names {
+		p := prefix(name)
+		if p == "" || len(suffixes[p]) < 2 {
+			ret = append(ret, name)
+		} else if !done[p] {
+			done[p] = true
+			ret = append(ret, p+"{"+strings.Join(suffixes[p], ",")+"}")
+		}
+	}
+	return ret
+}
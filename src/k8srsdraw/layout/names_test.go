@@ -0,0 +1,134 @@
+package layout
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseNameOrder(t *testing.T) {
+	tests := []struct {
+		s    string
+		want NameOrder
+		err  bool
+	}{
+		{"name", OrderByName, false},
+		{"age", OrderByAge, false},
+		{"moved", OrderByMoved, false},
+		{"", OrderByName, true},
+		{"Name", OrderByName, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseNameOrder(tt.s)
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v, want error %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamesAddDelete(t *testing.T) {
+	n := NewNames()
+	steps := []struct {
+		name string
+		op   func() bool
+		want bool
+		len  int
+	}{
+		{"add", func() bool { return n.Add("a") }, true, 1},
+		{"add again", func() bool { return n.Add("a") }, false, 1},
+		{"add moved", func() bool { return n.AddMoved("b") }, true, 2},
+		{"add moved again", func() bool { return n.AddMoved("b") }, false, 2},
+		{"add moved what was added", func() bool { return n.AddMoved("a") }, false, 2},
+		{"add what was moved", func() bool { return n.Add("b") }, false, 2},
+		{"has", func() bool { return n.Has("a") }, true, 2},
+		{"delete", func() bool { return n.Delete("a") }, true, 1},
+		{"delete again", func() bool { return n.Delete("a") }, false, 1},
+		{"has deleted", func() bool { return n.Has("a") }, false, 1},
+		{"delete missing", func() bool { return n.Delete("c") }, false, 1},
+	}
+	for _, s := range steps {
+		if got := s.op(); got != s.want {
+			t.Fatalf("%s: got %v, want %v", s.name, got, s.want)
+		}
+		if got := n.Len(); got != s.len {
+			t.Fatalf("%s: got %d names, want %d", s.name, got, s.len)
+		}
+	}
+}
+
+func TestNamesList(t *testing.T) {
+	// c, a and b come in in that order, then e and d are moved in, and f
+	// after a delete and re-add of a
+	n := NewNames()
+	n.Add("c")
+	n.Add("a")
+	n.Add("b")
+	n.AddMoved("e")
+	n.AddMoved("d")
+	n.Delete("a")
+	n.Add("a")
+	n.Add("f")
+	tests := []struct {
+		order NameOrder
+		want  []string
+	}{
+		{OrderByName, []string{"a", "b", "c", "d", "e", "f"}},
+		{OrderByAge, []string{"c", "b", "e", "d", "a", "f"}},
+		// most recently moved first, then the others by name
+		{OrderByMoved, []string{"d", "e", "a", "b", "c", "f"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.order), func(t *testing.T) {
+			if got := n.List(tt.order); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			// the order does not depend on map iteration
+			for i := 0; i < 10; i++ {
+				if got := n.List(tt.order); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("got %q on run %d, want %q", got, i, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNamesListEmpty(t *testing.T) {
+	if got := NewNames().List(OrderByMoved); len(got) != 0 {
+		t.Fatalf("got %q, want no names", got)
+	}
+}
+
+func TestAbbreviate(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		want  []string
+	}{
+		{"replica set", []string{"nginx-7d9f8-abcde", "nginx-7d9f8-fghij"}, []string{"nginx-7d9f8-{abcde,fghij}"}},
+		{"single member", []string{"nginx-7d9f8-abcde", "redis-0"}, []string{"nginx-7d9f8-abcde", "redis-0"}},
+		{"trailing dash", []string{"web-", "web-"}, []string{"web-", "web-"}},
+		{"trailing dash and members", []string{"web-", "web-a", "web-b"}, []string{"web-", "web-{a,b}"}},
+		{"leading dash", []string{"-a", "-b"}, []string{"-a", "-b"}},
+		{"no dash", []string{"a", "b"}, []string{"a", "b"}},
+		{"mixed prefixes keep their first position",
+			[]string{"a-1", "b-1", "c", "a-2", "b-2", "a-3"},
+			[]string{"a-{1,2,3}", "b-{1,2}", "c"}},
+		{"names between members",
+			[]string{"x", "a-1", "y", "a-2", "z"},
+			[]string{"x", "a-{1,2}", "y", "z"}},
+		{"prefix up to the last dash", []string{"a-b-1", "a-c-1"}, []string{"a-b-1", "a-c-1"}},
+		{"empty", []string{}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Abbreviate(tt.names); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
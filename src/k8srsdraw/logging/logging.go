@@ -0,0 +1,124 @@
+// Package logging hands out the slog loggers of every component. Loggers
+// may be taken before Setup is called: level and format are looked up on
+// every record, so Setup applies to them as well.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Options configures the loggers handed out by For.
+type Options struct {
+	// Level applies to the components missing from Levels.
+	Level  slog.Level
+	Levels map[string]slog.Level
+	// JSON selects slog.JSONHandler instead of slog.TextHandler.
+	JSON bool
+	// Output defaults to os.Stderr.
+	Output io.Writer
+}
+
+type config struct {
+	base   slog.Handler
+	level  slog.Level
+	levels map[string]slog.Level
+}
+
+func (c *config) levelOf(component string) slog.Level {
+	if l, ok := c.levels[component]; ok {
+		return l
+	}
+	return c.level
+}
+
+var current atomic.Pointer[config]
+
+func init() {
+	Setup(Options{Level: slog.LevelInfo})
+}
+
+// Setup replaces the configuration of every logger.
+func Setup(opts Options) {
+	out := opts.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	// filtering is done per component by handler.Enabled
+	hopts := &slog.HandlerOptions{Level: slog.Level(-100)}
+	var base slog.Handler
+	if opts.JSON {
+		base = slog.NewJSONHandler(out, hopts)
+	} else {
+		base = slog.NewTextHandler(out, hopts)
+	}
+	current.Store(&config{base: base, level: opts.Level, levels: opts.Levels})
+}
+
+// For returns the logger of component, its records carry a component
+// attribute.
+func For(component string) *slog.Logger {
+	h := &handler{component: component}
+	return slog.New(h).With("component", component)
+}
+
+// ParseLevels parses a spec like "info,socketclient=debug,window=warn": an
+// optional default level followed by per-component levels.
+func ParseLevels(spec string) (slog.Level, map[string]slog.Level, error) {
+	level := slog.LevelInfo
+	levels := make(map[string]slog.Level)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		component, name, found := strings.Cut(part, "=")
+		if !found {
+			name = component
+		}
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(name)); err != nil {
+			return level, nil, fmt.Errorf("bad log level %q: %v", part, err)
+		}
+		if found {
+			levels[component] = l
+		} else {
+			level = l
+		}
+	}
+	return level, levels, nil
+}
+
+// handler resolves the current configuration on every call and replays the
+// With calls made on it against the current base handler.
+type handler struct {
+	component string
+	ops       []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= current.Load().levelOf(h.component)
+}
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	var base slog.Handler = current.Load().base
+	for _, op := range h.ops {
+		base = op(base)
+	}
+	return base.Handle(ctx, r)
+}
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{component: h.component, ops: append(ops, op)}
+}
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(base slog.Handler) slog.Handler { return base.WithAttrs(attrs) })
+}
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(base slog.Handler) slog.Handler { return base.WithGroup(name) })
+}
@@ -0,0 +1,198 @@
+// Package metrics exports what the rescheduler did as Prometheus metrics.
+package metrics
+
+import (
+	"context"
+	"k8srsdraw/eventsource"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "k8srsdraw"
+
+var (
+	nodePodsDesc = prometheus.NewDesc(namespace+"_node_pods",
+		"Pods currently on the node.", []string{"node"}, nil)
+	queueDepthDesc = prometheus.NewDesc(namespace+"_workqueue_depth",
+		"Calls a consumer still has to handle.", []string{"consumer"}, nil)
+)
+
+// Exporter is an EventHandle which turns the calls it receives into metrics
+// served on /metrics.
+type Exporter struct {
+	mutex        sync.Mutex
+	addr         string
+	infos        eventsource.Infos
+	roundStart   time.Time
+	queueDepths  func() map[string]int
+	registry     *prometheus.Registry
+	reschedules  *prometheus.CounterVec
+	rounds       prometheus.Histogram
+	connects     prometheus.Counter
+	disconnects  prometheus.Counter
+	lastActivity prometheus.Gauge
+}
+
+func NewExporter(addr string) *Exporter {
+	e := &Exporter{
+		addr:     addr,
+		infos:    make(eventsource.Infos),
+		registry: prometheus.NewRegistry(),
+		reschedules: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reschedules_total",
+			Help:      "Reschedules reported by the rescheduler, by result.",
+		}, []string{"namespace", "from_node", "to_node", "result"}),
+		rounds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "round_duration_seconds",
+			Help:      "Time between the start and the stop marker of a reschedule round.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+		}),
+		connects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "connects_total",
+			Help:      "Connections established to the source, the first one included.",
+		}),
+		disconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "disconnects_total",
+			Help:      "Connections to the source which were lost.",
+		}),
+		lastActivity: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_activity_timestamp_seconds",
+			Help:      "When data last arrived from the source.",
+		}),
+	}
+	e.registry.MustRegister(e, e.reschedules, e.rounds, e.connects, e.disconnects, e.lastActivity,
+		collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return e
+}
+
+// SetQueueDepths sets where the work queue depths come from, typically
+// MultiEventHandle.QueueDepths. It is polled on every scrape.
+func (e *Exporter) SetQueueDepths(f func() map[string]int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.queueDepths = f
+}
+
+// Handler serves the metrics, for embedding in another server.
+func (e *Exporter) Handler() http.Handler {
+	return promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{})
+}
+
+// Run serves /metrics until ctx is done.
+func (e *Exporter) Run(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e.Handler())
+	srv := &http.Server{Addr: e.addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Describe and Collect implement prometheus.Collector for the metrics which
+// are read from the current state at scrape time.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nodePodsDesc
+	ch <- queueDepthDesc
+}
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mutex.Lock()
+	for nodeName, nodeInfo := range e.infos {
+		ch <- prometheus.MustNewConstMetric(nodePodsDesc, prometheus.GaugeValue,
+			float64(len(nodeInfo.PodInfos)), nodeName)
+	}
+	queueDepths := e.queueDepths
+	e.mutex.Unlock()
+	// queueDepths takes locks of its own, do not hold ours meanwhile
+	if queueDepths == nil {
+		return
+	}
+	for consumer, depth := range queueDepths() {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue,
+			float64(depth), consumer)
+	}
+}
+
+func (e *Exporter) Init(infos eventsource.Infos) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.infos = infos.Copy()
+}
+func (e *Exporter) AddNode(nodeName string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.infos.AddNode(nodeName)
+}
+func (e *Exporter) DeleteNode(nodeName string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.infos.DeleteNode(nodeName)
+}
+func (e *Exporter) AddPod(nodeName, podNamespace, podName string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.infos.AddPod(nodeName, podNamespace, podName)
+}
+func (e *Exporter) DeletePod(nodeName, podNamespace, podName string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.infos.DeletePod(nodeName, podNamespace, podName)
+}
+func (e *Exporter) ReschedulePod(ev eventsource.RescheduleEvent) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.infos.ReschedulePod(ev)
+	result := "success"
+	if !ev.Success {
+		result = "failure"
+	}
+	e.reschedules.WithLabelValues(ev.Namespace, ev.FromNodeName, ev.ToNodeName, result).Inc()
+}
+func (e *Exporter) GetCurNodeInfos() eventsource.Infos {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.infos.Copy()
+}
+
+func (e *Exporter) RoundStarted() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.roundStart = time.Now()
+}
+func (e *Exporter) RoundFinished() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	// a stop without a start happens when we connect in the middle of a
+	// round
+	if e.roundStart.IsZero() {
+		return
+	}
+	e.rounds.Observe(time.Since(e.roundStart).Seconds())
+	e.roundStart = time.Time{}
+}
+
+func (e *Exporter) Connected() {
+	e.connects.Inc()
+}
+func (e *Exporter) Disconnected(err error) {
+	e.disconnects.Inc()
+}
+func (e *Exporter) Alive(t time.Time) {
+	e.lastActivity.Set(float64(t.UnixNano()) / 1e9)
+}
@@ -0,0 +1,187 @@
+// mockserver speaks the rescheduler debug protocol on a fake cluster so the
+// visualizer can be exercised without a real rescheduler.
+package main
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"k8srsdraw/eventsource"
+	"k8srsdraw/logging"
+	"k8srsdraw/socketclient"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	listen     = flag.String("listen", ":8888", "address to listen on")
+	nodeNum    = flag.Int("nodes", 5, "number of fake nodes")
+	nsNum      = flag.Int("namespaces", 4, "number of fake namespaces")
+	podNum     = flag.Int("pods", 3, "pods per namespace")
+	interval   = flag.Duration("interval", 3*time.Second, "time between two reschedule rounds")
+	heartbeat  = flag.Duration("heartbeat", 5*time.Second, "heartbeat period, 0 disables heartbeats")
+	tlsCert    = flag.String("tls-cert", "", "server certificate, enables TLS")
+	tlsKey     = flag.String("tls-key", "", "server private key")
+	clientCA   = flag.String("client-ca", "", "require client certificates signed by this CA")
+	token      = flag.String("token", "", "require this bearer token from clients, needs -tls-cert")
+	genCertDir = flag.String("gencert", "", "generate a test CA, server and client certificates into this directory and exit")
+	certHosts  = flag.String("hosts", "127.0.0.1,localhost", "comma separated hosts put in the generated server certificate")
+)
+
+var logger = logging.For("mockserver")
+
+func frame(id, payload string) string {
+	return id + "->" + payload + "#"
+}
+
+func snapshotFrame(c *eventsource.FakeCluster) string {
+	b, _ := json.Marshal(c.Infos())
+	return frame(socketclient.INFOTYPE_NODEINFO, string(b))
+}
+
+// sendBatch writes one batch of frames and waits for the client ack.
+func sendBatch(con net.Conn, batch string) error {
+	if _, err := con.Write([]byte(batch)); err != nil {
+		return err
+	}
+	ack := make([]byte, 1)
+	_, err := con.Read(ack)
+	return err
+}
+
+func checkToken(con net.Conn) error {
+	buf := make([]byte, 4096)
+	strBuf := ""
+	for !strings.HasSuffix(strBuf, "#") {
+		n, err := con.Read(buf)
+		if err != nil {
+			return err
+		}
+		strBuf += string(buf[:n])
+	}
+	frames := socketclient.SplitFrames(strBuf)
+	if len(frames) != 1 || !strings.HasPrefix(frames[0], socketclient.INFOTYPE_AUTH+"->Bearer ") {
+		return fmt.Errorf("no token")
+	}
+	got := strings.TrimPrefix(frames[0], socketclient.INFOTYPE_AUTH+"->Bearer ")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(*token)) != 1 {
+		return fmt.Errorf("bad token")
+	}
+	_, err := con.Write([]byte(frame(socketclient.INFOTYPE_AUTH, socketclient.AUTH_OK)))
+	return err
+}
+
+func serve(con net.Conn) {
+	defer con.Close()
+	logger.Info("client connected", "addr", con.RemoteAddr())
+	if *token != "" {
+		if err := checkToken(con); err != nil {
+			logger.Warn("client rejected", "addr", con.RemoteAddr(), "err", err)
+			return
+		}
+	}
+	c := eventsource.NewFakeCluster(*nodeNum, *nsNum, *podNum)
+	if err := sendBatch(con, snapshotFrame(c)); err != nil {
+		logger.Info("client gone", "addr", con.RemoteAddr(), "err", err)
+		return
+	}
+	var beat <-chan time.Time
+	if *heartbeat > 0 {
+		t := time.NewTicker(*heartbeat)
+		defer t.Stop()
+		beat = t.C
+	}
+	round := time.NewTicker(*interval)
+	defer round.Stop()
+	for {
+		select {
+		case now := <-beat:
+			ms := now.UnixNano() / int64(time.Millisecond)
+			if err := sendBatch(con, frame(socketclient.INFOTYPE_HEARTBEAT, fmt.Sprint(ms))); err != nil {
+				logger.Info("client gone", "addr", con.RemoteAddr(), "err", err)
+				return
+			}
+			continue
+		case <-round.C:
+		}
+		batch := frame(socketclient.INFOTYPE_RESCHEDULE_STARTONERESCHEDULE, "")
+		if m, ok := c.Reschedule(); ok {
+			payload := strings.Join([]string{m.Namespace, m.FromPodName, m.ToPodName, m.FromNodeName, m.ToNodeName}, ":")
+			batch += frame(socketclient.INFOTYPE_RESCHEDULE_OK, payload)
+		}
+		batch += snapshotFrame(c)
+		batch += frame(socketclient.INFOTYPE_RESCHEDULE_STOPONERESCHEDULE, "")
+		if err := sendBatch(con, batch); err != nil {
+			logger.Info("client gone", "addr", con.RemoteAddr(), "err", err)
+			return
+		}
+	}
+}
+
+func listener() (net.Listener, error) {
+	if *tlsCert == "" {
+		return net.Listen("tcp", *listen)
+	}
+	cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if *clientCA != "" {
+		caBytes, err := os.ReadFile(*clientCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("no certificate found in %s", *clientCA)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tls.Listen("tcp", *listen, cfg)
+}
+
+func main() {
+	flag.Parse()
+	if *genCertDir != "" {
+		if err := generateCerts(*genCertDir, strings.Split(*certHosts, ",")); err != nil {
+			logger.Error("generate certificates failed", "err", err)
+			os.Exit(-1)
+		}
+		return
+	}
+	// like the client, never take a token in clear text
+	if *token != "" && *tlsCert == "" {
+		logger.Error("-token requires -tls-cert and -tls-key")
+		os.Exit(-1)
+	}
+	// the client rejects snapshots without nodes
+	if *nodeNum <= 0 {
+		logger.Error("-nodes must be positive", "nodes", *nodeNum)
+		os.Exit(-1)
+	}
+	if *interval <= 0 {
+		logger.Error("-interval must be positive", "interval", *interval)
+		os.Exit(-1)
+	}
+	l, err := listener()
+	if err != nil {
+		logger.Error("listen failed", "addr", *listen, "err", err)
+		os.Exit(-1)
+	}
+	logger.Info("mock rescheduler listening", "addr", l.Addr())
+	for {
+		con, err := l.Accept()
+		if err != nil {
+			logger.Warn("accept failed", "err", err)
+			continue
+		}
+		go serve(con)
+	}
+}
@@ -0,0 +1,245 @@
+// Package report summarizes a debugging session: every reschedule, the
+// reschedule rounds and the final distribution of the pods.
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"k8srsdraw/eventsource"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Reschedule is one reschedule of the session. Round is the index in
+// Report.Rounds of the round it happened in, -1 when outside of any round.
+type Reschedule struct {
+	eventsource.RescheduleEvent
+	Round int `json:"round"`
+}
+
+// Round aggregates the reschedules between a start and a stop marker. End
+// is zero for a round still running when the report was taken.
+type Round struct {
+	Start     time.Time     `json:"start"`
+	End       time.Time     `json:"end"`
+	Duration  time.Duration `json:"duration"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+}
+
+type Report struct {
+	Start        time.Time         `json:"start"`
+	End          time.Time         `json:"end"`
+	Reschedules  []Reschedule      `json:"reschedules"`
+	Rounds       []Round           `json:"rounds"`
+	Distribution eventsource.Infos `json:"distribution"`
+}
+
+// Recorder is an EventHandle collecting what a Report needs.
+type Recorder struct {
+	mutex       sync.Mutex
+	start       time.Time
+	infos       eventsource.Infos
+	reschedules []Reschedule
+	rounds      []Round
+	inRound     bool
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		start: time.Now(),
+		infos: make(eventsource.Infos),
+	}
+}
+
+// Report returns a copy of the session so far.
+func (r *Recorder) Report() *Report {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	now := time.Now()
+	ret := &Report{
+		Start:        r.start,
+		End:          now,
+		Reschedules:  append([]Reschedule(nil), r.reschedules...),
+		Rounds:       append([]Round(nil), r.rounds...),
+		Distribution: r.infos.Copy(),
+	}
+	if r.inRound {
+		last := &ret.Rounds[len(ret.Rounds)-1]
+		last.Duration = now.Sub(last.Start)
+	}
+	return ret
+}
+
+func (r *Recorder) Init(infos eventsource.Infos) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.infos = infos.Copy()
+}
+func (r *Recorder) AddNode(nodeName string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.infos.AddNode(nodeName)
+}
+func (r *Recorder) DeleteNode(nodeName string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.infos.DeleteNode(nodeName)
+}
+func (r *Recorder) AddPod(nodeName, podNamespace, podName string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.infos.AddPod(nodeName, podNamespace, podName)
+}
+func (r *Recorder) DeletePod(nodeName, podNamespace, podName string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.infos.DeletePod(nodeName, podNamespace, podName)
+}
+func (r *Recorder) ReschedulePod(ev eventsource.RescheduleEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.infos.ReschedulePod(ev)
+	round := -1
+	if r.inRound {
+		round = len(r.rounds) - 1
+		if ev.Success {
+			r.rounds[round].Succeeded++
+		} else {
+			r.rounds[round].Failed++
+		}
+	}
+	r.reschedules = append(r.reschedules, Reschedule{RescheduleEvent: ev, Round: round})
+}
+func (r *Recorder) GetCurNodeInfos() eventsource.Infos {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.infos.Copy()
+}
+
+func (r *Recorder) RoundStarted() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.finishRound()
+	r.rounds = append(r.rounds, Round{Start: time.Now()})
+	r.inRound = true
+}
+func (r *Recorder) RoundFinished() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.finishRound()
+}
+
+// finishRound closes the running round if any, the caller must hold
+// r.mutex.
+func (r *Recorder) finishRound() {
+	if !r.inRound {
+		return
+	}
+	last := &r.rounds[len(r.rounds)-1]
+	last.End = time.Now()
+	last.Duration = last.End.Sub(last.Start)
+	r.inRound = false
+}
+
+func (rp *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rp)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// WriteReschedulesCSV writes one line per reschedule.
+func (rp *Report) WriteReschedulesCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "round", "namespace", "fromNode", "fromPod", "toNode", "toPod", "success", "reason"})
+	for _, r := range rp.Reschedules {
+		cw.Write([]string{formatTime(r.Time), strconv.Itoa(r.Round), r.Namespace, r.FromNodeName, r.FromPodName,
+			r.ToNodeName, r.ToPodName, strconv.FormatBool(r.Success), r.Reason})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteRoundsCSV writes one line per round.
+func (rp *Report) WriteRoundsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"round", "start", "end", "durationSeconds", "succeeded", "failed"})
+	for i, r := range rp.Rounds {
+		cw.Write([]string{strconv.Itoa(i), formatTime(r.Start), formatTime(r.End),
+			strconv.FormatFloat(r.Duration.Seconds(), 'f', 3, 64), strconv.Itoa(r.Succeeded), strconv.Itoa(r.Failed)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteDistributionCSV writes one line per pod, sorted by node, namespace
+// and name. Empty nodes get a line with empty pod columns.
+func (rp *Report) WriteDistributionCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"node", "namespace", "pod"})
+	nodeNames := make([]string, 0, len(rp.Distribution))
+	for name := range rp.Distribution {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+	for _, nodeName := range nodeNames {
+		pods := append([]eventsource.PodInfos(nil), rp.Distribution[nodeName].PodInfos...)
+		if len(pods) == 0 {
+			cw.Write([]string{nodeName, "", ""})
+			continue
+		}
+		sort.Slice(pods, func(i, j int) bool {
+			if pods[i].Namespace != pods[j].Namespace {
+				return pods[i].Namespace < pods[j].Namespace
+			}
+			return pods[i].Name < pods[j].Name
+		})
+		for _, p := range pods {
+			cw.Write([]string{nodeName, p.Namespace, p.Name})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteFiles writes the report next to base: base.json,
+// base-reschedules.csv, base-rounds.csv and base-distribution.csv.
+func (rp *Report) WriteFiles(base string) error {
+	for _, f := range []struct {
+		suffix string
+		write  func(io.Writer) error
+	}{
+		{".json", rp.WriteJSON},
+		{"-reschedules.csv", rp.WriteReschedulesCSV},
+		{"-rounds.csv", rp.WriteRoundsCSV},
+		{"-distribution.csv", rp.WriteDistributionCSV},
+	} {
+		if err := writeFile(base+f.suffix, f.write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -1,25 +1,296 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"k8srsdraw/balance"
+	"k8srsdraw/drawapi"
 	"k8srsdraw/eventhandler"
+	"k8srsdraw/eventsource"
+	"k8srsdraw/kubesource"
+	"k8srsdraw/layout"
+	"k8srsdraw/logging"
+	"k8srsdraw/metrics"
+	"k8srsdraw/report"
 	"k8srsdraw/socketclient"
+	"k8srsdraw/theme"
+	"k8srsdraw/tui"
+	"k8srsdraw/web"
+	"k8srsdraw/window"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
+var (
+	port       = flag.String("port", "8888", "rescheduler debug port")
+	useTLS     = flag.Bool("tls", false, "connect with TLS")
+	caFile     = flag.String("ca", "", "CA used to verify the server, defaults to the system pool")
+	certFile   = flag.String("cert", "", "client certificate")
+	keyFile    = flag.String("key", "", "client private key")
+	serverName = flag.String("server-name", "", "server name expected in the server certificate")
+	insecure   = flag.Bool("insecure", false, "do not verify the server certificate")
+	token      = flag.String("token", "", "bearer token sent to the server, needs -tls")
+	tokenFile  = flag.String("token-file", "", "read the bearer token from this file")
+	readTO     = flag.Duration("read-timeout", 0, "reconnect when nothing arrived for this long, 0 disables")
+	writeTO    = flag.Duration("write-timeout", 10*time.Second, "timeout of writes to the server, 0 disables")
+	source     = flag.String("source", "socket", "where events come from: socket, kube, replay or mock")
+	kubeconfig = flag.String("kubeconfig", "", "kubeconfig of the cluster watched by -source=kube, empty for in-cluster")
+	replayFile = flag.String("replay-file", "", "file played back by -source=replay")
+	replaySpd  = flag.Float64("replay-speed", 1, "speed factor of -source=replay, 0 plays without delays")
+	mockNodes  = flag.Int("mock-nodes", 5, "number of nodes of -source=mock")
+	mockNs     = flag.Int("mock-namespaces", 4, "number of namespaces of -source=mock")
+	mockPods   = flag.Int("mock-pods", 3, "pods per namespace of -source=mock")
+	mockIntv   = flag.Duration("mock-interval", 3*time.Second, "time between two reschedules of -source=mock, 0 or less for the default")
+	ui         = flag.String("ui", "x", "frontend: x (X11 window), tui (terminal) or none")
+	webAddr    = flag.String("web", "", "serve the live view to browsers on this address, e.g. :8080")
+	metricAddr = flag.String("metrics", "", "serve Prometheus metrics on this address, e.g. :9090")
+	reportBase = flag.String("report", "", "write a session report to <report>.json and <report>-*.csv on exit, on SIGUSR1 and on the r key of the window")
+	recordFile = flag.String("record", "", "record every event to this file, for -source=replay")
+	logEvents  = flag.Bool("log-events", false, "print every event")
+	logLevel   = flag.String("log-level", "info", "log levels, e.g. \"info,socketclient=debug\"; components: socketclient, workqueue, window, drawapi, events, record, main")
+	logJSON    = flag.Bool("log-json", false, "log as JSON lines")
+	logFile    = flag.String("log-file", "", "append logs to this file instead of stderr, -ui=tui logs to "+tuiLogFile+" by default")
+	maxNs      = flag.Int("max-namespaces", 1, "a node holding more namespaces is painted red")
+	nodeCap    = flag.Int("node-capacity", 0, "pods a node can hold, 0 if unknown")
+	maxUtil    = flag.Float64("max-utilization", 0.9, "a node using more of -node-capacity is painted red")
+	staleAfter = flag.Duration("stale-after", 30*time.Second, "warn that the view is stale after this long without data")
+	themeSpec  = flag.String("theme", "dark", "colors of the X window: "+strings.Join(theme.Presets(), ", ")+" or a JSON theme file")
+	fps        = flag.Int("fps", 30, "frame rate of the X window animations")
+	fontFile   = flag.String("font", "", "TrueType font of the X window, empty for the embedded one")
+	fontFbs    = flag.String("font-fallback", "", "comma separated fonts for the glyphs -font lacks, tried before the installed CJK fonts")
+	podOrder   = flag.String("pod-order", "name", "order of the pod names in the labels of the X window: name, age or moved")
+	abbrevPods = flag.Bool("abbreviate-pods", true, "shorten the names of the pods of a ReplicaSet in the labels of the X window, e.g. nginx-7d9f8-{abcde,fghij}")
+)
+
+// tuiLogFile is where -ui=tui logs unless -log-file says otherwise.
+const tuiLogFile = "rsdebug.log"
+
+func newSocketSource(sip string) (eventsource.EventSource, error) {
+	opts := socketclient.Options{
+		Token:        *token,
+		ReadTimeout:  *readTO,
+		WriteTimeout: *writeTO,
+	}
+	if *tokenFile != "" {
+		b, err := os.ReadFile(*tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("read token file: %v", err)
+		}
+		opts.Token = strings.TrimSpace(string(b))
+	}
+	withTLS := *useTLS || *caFile != "" || *certFile != ""
+	if opts.Token != "" && !withTLS {
+		return nil, fmt.Errorf("%v, add -tls", socketclient.ErrTokenWithoutTLS)
+	}
+	if withTLS {
+		opts.TLS = &socketclient.TLSOptions{
+			CAFile:             *caFile,
+			CertFile:           *certFile,
+			KeyFile:            *keyFile,
+			ServerName:         *serverName,
+			InsecureSkipVerify: *insecure,
+		}
+	}
+	return socketclient.NewSource(sip, *port, opts), nil
+}
+
+func newSource(sip string) (eventsource.EventSource, error) {
+	switch *source {
+	case "socket":
+		return newSocketSource(sip)
+	case "kube":
+		return kubesource.NewSourceFromKubeconfig(*kubeconfig, kubesource.Options{})
+	case "replay":
+		if *replayFile == "" {
+			return nil, fmt.Errorf("-source=replay needs -replay-file")
+		}
+		return eventsource.NewReplaySource(*replayFile, *replaySpd), nil
+	case "mock":
+		return eventsource.NewMockSource(*mockNodes, *mockNs, *mockPods, *mockIntv), nil
+	}
+	return nil, fmt.Errorf("unknown source %q", *source)
+}
+
 func main() {
+	flag.Usage = func() {
+		fmt.Printf("%s [flags] serverip\nFor Example: %s 127.0.0.1\n", os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	level, levels, err := logging.ParseLevels(*logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(-1)
+	}
+	// the terminal frontend owns the terminal, logs would scribble over it
+	if *logFile == "" && *ui == "tui" {
+		*logFile = tuiLogFile
+	}
+	var logOut io.Writer
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "open log file: %v\n", err)
+			os.Exit(-1)
+		}
+		defer f.Close()
+		logOut = f
+	}
+	logging.Setup(logging.Options{Level: level, Levels: levels, JSON: *logJSON, Output: logOut})
+	logger := logging.For("main")
+	balance.NodeThresholds = balance.Thresholds{
+		MaxNamespaces:  *maxNs,
+		Capacity:       *nodeCap,
+		MaxUtilization: *maxUtil,
+	}
+
 	var sip = "10.19.132.220"
-	if len(os.Args) == 2 {
-		sip = os.Args[1]
-	} else if len(os.Args) != 1 {
-		fmt.Printf("%s serverip\nFor Example: %s 127.0.0.1\n", os.Args[0], os.Args[0])
+	if flag.NArg() == 1 {
+		sip = flag.Arg(0)
+	} else if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(-1)
+	}
+	src, err := newSource(sip)
+	if err != nil {
+		logger.Error("create source failed", "source", *source, "err", err)
+		os.Exit(-1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	var wg sync.WaitGroup
+	handles := make([]eventsource.EventHandle, 0)
+	var recorder *report.Recorder
+	// the signal, the r key and the exit may all write the same files
+	var reportMutex sync.Mutex
+	writeReport := func() {
+		reportMutex.Lock()
+		defer reportMutex.Unlock()
+		if err := recorder.Report().WriteFiles(*reportBase); err != nil {
+			logger.Error("write report failed", "err", err)
+			return
+		}
+		logger.Info("report written", "base", *reportBase)
+	}
+	if *reportBase != "" {
+		recorder = report.NewRecorder()
+		handles = append(handles, recorder)
+		usr1 := make(chan os.Signal, 1)
+		signal.Notify(usr1, syscall.SIGUSR1)
+		go func() {
+			for range usr1 {
+				writeReport()
+			}
+		}()
+	}
+	switch *ui {
+	case "x":
+		th, err := theme.Get(*themeSpec)
+		if err != nil {
+			logger.Error("load theme failed", "theme", *themeSpec, "err", err)
+			os.Exit(-1)
+		}
+		if *fps > 0 {
+			drawapi.DefaultFPS = *fps
+		}
+		var fallbacks []string
+		if *fontFbs != "" {
+			fallbacks = strings.Split(*fontFbs, ",")
+		}
+		fonts, err := drawapi.LoadFonts(*fontFile, fallbacks)
+		if err != nil {
+			logger.Error("load font failed", "err", err)
+			os.Exit(-1)
+		}
+		drawapi.SetDefaultFonts(fonts)
+		order, err := layout.ParseNameOrder(*podOrder)
+		if err != nil {
+			logger.Error("bad -pod-order", "err", err)
+			os.Exit(-1)
+		}
+		window.PodNameOrder = order
+		window.AbbreviatePodNames = *abbrevPods
+		deh, err := eventhandler.NewDrawEventHandle(800, 400, th)
+		if err != nil {
+			logger.Error("open window failed", "err", err)
+			os.Exit(-1)
+		}
+		deh.SetStaleThreshold(*staleAfter)
+		if recorder != nil {
+			// keys are handled on the X event loop, keep the disk off it
+			deh.OnKey("r", func() { go writeReport() })
+		}
+		handles = append(handles, deh)
+	case "tui":
+		t := tui.NewTUI(os.Stdout)
+		t.SetStaleThreshold(*staleAfter)
+		handles = append(handles, t)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.Run(ctx)
+		}()
+	case "none":
+	default:
+		logger.Error("unknown ui", "ui", *ui)
 		os.Exit(-1)
 	}
-	deh := eventhandler.NewDrawEventHandle(800, 400)
-	for {
-		sc := socketclient.NewSClient(sip, "8888", deh)
-		sc.Run()
-		time.Sleep(3 * time.Second)
+	if *logEvents {
+		handles = append(handles, eventhandler.NewLogEventHandle())
+	}
+	if *webAddr != "" {
+		srv := web.NewServer(*webAddr)
+		handles = append(handles, srv)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Run(ctx); err != nil {
+				logger.Error("web server failed", "err", err)
+			}
+		}()
+	}
+	var exporter *metrics.Exporter
+	if *metricAddr != "" {
+		exporter = metrics.NewExporter(*metricAddr)
+		handles = append(handles, exporter)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := exporter.Run(ctx); err != nil {
+				logger.Error("metrics server failed", "err", err)
+			}
+		}()
+	}
+	if *recordFile != "" {
+		f, err := os.Create(*recordFile)
+		if err != nil {
+			logger.Error("create record file failed", "err", err)
+			os.Exit(-1)
+		}
+		defer f.Close()
+		handles = append(handles, eventhandler.NewRecordEventHandle(f))
+	}
+	multi := eventhandler.NewMultiEventHandle(handles...)
+	if exporter != nil {
+		exporter.SetQueueDepths(multi.QueueDepths)
+	}
+	err = src.Run(ctx, multi)
+	// the report must hold the calls still queued for the consumers
+	multi.Close()
+	cancel()
+	wg.Wait()
+	if recorder != nil {
+		writeReport()
+	}
+	if err != nil {
+		logger.Error("source failed", "source", *source, "err", err)
+		os.Exit(-1)
 	}
 }
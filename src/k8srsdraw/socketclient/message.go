@@ -0,0 +1,209 @@
+package socketclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"k8srsdraw/eventsource"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a parsed message received from the rescheduler.
+type Message interface {
+	InfoType() string
+}
+
+// NodeInfoMessage is a full snapshot of the node -> pods distribution.
+type NodeInfoMessage struct {
+	Infos eventsource.Infos
+}
+
+// RescheduleResult reports the outcome of moving one pod. Success follows
+// the message type, Time is when the message was parsed unless the JSON
+// form carries one.
+type RescheduleResult struct {
+	eventsource.RescheduleEvent
+}
+
+// RoundMarker marks the start or the end of one reschedule round, it has
+// no payload.
+type RoundMarker struct {
+	Start bool
+}
+
+// TextMessage is a free form message from the rescheduler.
+type TextMessage struct {
+	Text string
+}
+
+// AuthReply is the answer of the server to the bearer token, AUTH_OK when
+// the token was accepted.
+type AuthReply struct {
+	Status string
+}
+
+// HeartbeatMessage keeps an idle connection alive. The payload optionally
+// carries the server time in unix milliseconds.
+type HeartbeatMessage struct {
+	Time time.Time
+}
+
+func (m *NodeInfoMessage) InfoType() string {
+	return INFOTYPE_NODEINFO
+}
+func (m *RescheduleResult) InfoType() string {
+	if m.Success {
+		return INFOTYPE_RESCHEDULE_OK
+	}
+	return INFOTYPE_RESCHEDULE_FAIL
+}
+func (m *RoundMarker) InfoType() string {
+	if m.Start {
+		return INFOTYPE_RESCHEDULE_STARTONERESCHEDULE
+	}
+	return INFOTYPE_RESCHEDULE_STOPONERESCHEDULE
+}
+func (m *TextMessage) InfoType() string {
+	return INFOTYPE_MESSAGE
+}
+func (m *HeartbeatMessage) InfoType() string {
+	return INFOTYPE_HEARTBEAT
+}
+func (m *AuthReply) InfoType() string {
+	return INFOTYPE_AUTH
+}
+
+// SplitFrames splits what was read from the socket into its frames, the
+// whitespace around frames is dropped.
+func SplitFrames(batch string) []string {
+	ret := make([]string, 0)
+	for _, str := range strings.Split(batch, "#") {
+		if str = strings.TrimSpace(str); str != "" {
+			ret = append(ret, str)
+		}
+	}
+	return ret
+}
+
+// ParseFrame parses one "id->payload" frame as read from the socket.
+func ParseFrame(frame string) (Message, error) {
+	strs := strings.SplitN(frame, "->", 2)
+	if len(strs) != 2 {
+		return nil, fmt.Errorf("malformed frame %q: missing \"->\" separator", frame)
+	}
+	return ParseMessage(strs[0], strs[1])
+}
+
+// ParseMessage parses the payload of a message of the given INFOTYPE. The
+// message is nil whenever the error is not.
+func ParseMessage(id, payload string) (Message, error) {
+	var msg Message
+	var err error
+	switch id {
+	case INFOTYPE_NODEINFO:
+		msg, err = parseNodeInfo(payload)
+	case INFOTYPE_RESCHEDULE_OK:
+		msg, err = parseRescheduleResult(payload, true)
+	case INFOTYPE_RESCHEDULE_FAIL:
+		msg, err = parseRescheduleResult(payload, false)
+	case INFOTYPE_RESCHEDULE_STARTONERESCHEDULE:
+		msg, err = parseRoundMarker(payload, true)
+	case INFOTYPE_RESCHEDULE_STOPONERESCHEDULE:
+		msg, err = parseRoundMarker(payload, false)
+	case INFOTYPE_MESSAGE:
+		msg = &TextMessage{Text: payload}
+	case INFOTYPE_HEARTBEAT:
+		msg, err = parseHeartbeat(payload)
+	case INFOTYPE_AUTH:
+		msg = &AuthReply{Status: strings.TrimSpace(payload)}
+	default:
+		err = fmt.Errorf("unknown message type %q", id)
+	}
+	if err != nil {
+		// a nil *T in msg would not compare equal to nil
+		return nil, err
+	}
+	return msg, nil
+}
+
+func parseNodeInfo(payload string) (*NodeInfoMessage, error) {
+	var infos eventsource.Infos
+	if err := json.Unmarshal([]byte(payload), &infos); err != nil {
+		return nil, fmt.Errorf("invalid node info: %v", err)
+	}
+	// the client deletes every node missing from a snapshot, an empty one
+	// would wipe the whole view
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("invalid node info %q: no nodes", payload)
+	}
+	for key, nodeInfo := range infos {
+		if nodeInfo == nil {
+			return nil, fmt.Errorf("invalid node info: node %q has no content", key)
+		}
+		if nodeInfo.NodeName == "" {
+			nodeInfo.NodeName = key
+		} else if nodeInfo.NodeName != key {
+			return nil, fmt.Errorf("invalid node info: node %q is stored under key %q", nodeInfo.NodeName, key)
+		}
+		for i, podInfo := range nodeInfo.PodInfos {
+			if podInfo.Namespace == "" || podInfo.Name == "" {
+				return nil, fmt.Errorf("invalid node info: pod %d on node %q has empty name or namespace", i, key)
+			}
+		}
+	}
+	return &NodeInfoMessage{Infos: infos}, nil
+}
+
+// parseRescheduleResult accepts either the JSON form
+// {"namespace":..,"fromPod":..,"toPod":..,"fromNode":..,"toNode":..,"reason":..}
+// or the legacy form namespace:fromPod:toPod:fromNode:toNode. Only the JSON
+// form can carry names which contain ':' and a reason.
+func parseRescheduleResult(payload string, success bool) (*RescheduleResult, error) {
+	ret := &RescheduleResult{}
+	if strings.HasPrefix(strings.TrimSpace(payload), "{") {
+		if err := json.Unmarshal([]byte(payload), &ret.RescheduleEvent); err != nil {
+			return nil, fmt.Errorf("invalid reschedule result: %v", err)
+		}
+	} else {
+		names := strings.Split(payload, ":")
+		if len(names) != 5 {
+			return nil, fmt.Errorf("invalid reschedule result %q: want 5 ':' separated fields, got %d", payload, len(names))
+		}
+		ret.Namespace, ret.FromPodName, ret.ToPodName, ret.FromNodeName, ret.ToNodeName = names[0], names[1], names[2], names[3], names[4]
+	}
+	ret.Success = success
+	if ret.Time.IsZero() {
+		ret.Time = time.Now()
+	}
+	for _, f := range []struct{ name, value string }{
+		{"namespace", ret.Namespace},
+		{"fromPod", ret.FromPodName},
+		{"toPod", ret.ToPodName},
+		{"fromNode", ret.FromNodeName},
+		{"toNode", ret.ToNodeName},
+	} {
+		if f.value == "" {
+			return nil, fmt.Errorf("invalid reschedule result %q: empty %s", payload, f.name)
+		}
+	}
+	return ret, nil
+}
+
+func parseRoundMarker(payload string, start bool) (*RoundMarker, error) {
+	if strings.TrimSpace(payload) != "" {
+		return nil, fmt.Errorf("invalid round marker %q: want no payload", payload)
+	}
+	return &RoundMarker{Start: start}, nil
+}
+
+func parseHeartbeat(payload string) (*HeartbeatMessage, error) {
+	if payload == "" {
+		return &HeartbeatMessage{}, nil
+	}
+	ms, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid heartbeat %q: %v", payload, err)
+	}
+	return &HeartbeatMessage{Time: time.Unix(0, ms*int64(time.Millisecond))}, nil
+}
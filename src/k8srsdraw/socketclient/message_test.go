@@ -0,0 +1,145 @@
+package socketclient
+
+import (
+	"k8srsdraw/eventsource"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitFrames(t *testing.T) {
+	tests := []struct {
+		name  string
+		batch string
+		want  []string
+	}{
+		{"one frame", "7->#", []string{"7->"}},
+		{"several frames", "5->#3->a:b:c:d:e#6->#", []string{"5->", "3->a:b:c:d:e", "6->"}},
+		{"whitespace around frames", " 5-># \n6->#\r\n", []string{"5->", "6->"}},
+		{"empty frames", "##7->##", []string{"7->"}},
+		{"no frame", "", []string{}},
+		{"only whitespace", " \n", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitFrames(tt.batch); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFrame(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	moved := func(success bool, ns, fromPod, toPod, fromNode, toNode, reason string, when time.Time) *RescheduleResult {
+		return &RescheduleResult{eventsource.RescheduleEvent{
+			Namespace:    ns,
+			FromPodName:  fromPod,
+			ToPodName:    toPod,
+			FromNodeName: fromNode,
+			ToNodeName:   toNode,
+			Time:         when,
+			Reason:       reason,
+			Success:      success,
+		}}
+	}
+	tests := []struct {
+		name  string
+		frame string
+		want  Message
+		// err is a part of the error, "" when the frame parses
+		err string
+	}{
+		{"node info", `1->{"n1":{"NodeName":"n1","PodInfos":[{"Name":"a","Namespace":"default"}]},"n2":{"PodInfos":[]}}`,
+			&NodeInfoMessage{Infos: eventsource.Infos{
+				"n1": {NodeName: "n1", PodInfos: []eventsource.PodInfos{{Name: "a", Namespace: "default"}}},
+				"n2": {NodeName: "n2", PodInfos: []eventsource.PodInfos{}},
+			}}, ""},
+		{"null node info", "1->null", nil, "no nodes"},
+		{"empty node info", "1->{}", nil, "no nodes"},
+		{"node info without payload", "1->", nil, "invalid node info"},
+		{"node info with bad json", `1->{"n1":`, nil, "invalid node info"},
+		{"node without content", `1->{"n1":null}`, nil, "has no content"},
+		{"node under another key", `1->{"n1":{"NodeName":"n2"}}`, nil, "stored under key"},
+		{"pod without name", `1->{"n1":{"PodInfos":[{"Namespace":"default"}]}}`, nil, "empty name or namespace"},
+
+		{"legacy success", "3->default:a:b:n1:n2", moved(true, "default", "a", "b", "n1", "n2", "", time.Time{}), ""},
+		{"legacy failure", "4->default:a:b:n1:n2", moved(false, "default", "a", "b", "n1", "n2", "", time.Time{}), ""},
+		{"legacy with too few fields", "3->a:b", nil, "want 5 ':' separated fields, got 2"},
+		{"legacy with too many fields", "3->default:a:b:c:n1:n2", nil, "got 6"},
+		{"legacy with an empty field", "3->default::b:n1:n2", nil, "empty fromPod"},
+		{"json", `3->{"namespace":"default","fromPod":"a:1","toPod":"b:2","fromNode":"n1","toNode":"n2","reason":"spread","time":"2024-05-01T12:00:00Z"}`,
+			moved(true, "default", "a:1", "b:2", "n1", "n2", "spread", at), ""},
+		{"json failure", `4->{"namespace":"default","fromPod":"a","toPod":"b","fromNode":"n1","toNode":"n2","success":true}`,
+			moved(false, "default", "a", "b", "n1", "n2", "", time.Time{}), ""},
+		{"json with an empty field", `3->{"namespace":"default","fromPod":"a","toPod":"b","fromNode":"n1"}`, nil, "empty toNode"},
+		{"bad json", `3->{"namespace":`, nil, "invalid reschedule result"},
+
+		{"round start", "5->", &RoundMarker{Start: true}, ""},
+		{"round stop", "6->", &RoundMarker{Start: false}, ""},
+		{"round start with payload", "5->garbage", nil, "want no payload"},
+		{"round stop with payload", "6->garbage", nil, "want no payload"},
+
+		{"text", "2->hello -> world", &TextMessage{Text: "hello -> world"}, ""},
+		{"heartbeat", "7->", &HeartbeatMessage{}, ""},
+		{"heartbeat with time", "7->1714564800000", &HeartbeatMessage{Time: time.Unix(1714564800, 0)}, ""},
+		{"bad heartbeat", "7->soon", nil, "invalid heartbeat"},
+		{"auth reply", "0-> ok ", &AuthReply{Status: AUTH_OK}, ""},
+		{"unknown type", "9->x", nil, "unknown message type"},
+		{"no separator", "1{}", nil, "missing \"->\" separator"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFrame(tt.frame)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.err)
+				}
+				if got != nil {
+					t.Fatalf("got message %#v along with the error, want nil", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// results without a time are stamped when parsed
+			if r, ok := got.(*RescheduleResult); ok && tt.want.(*RescheduleResult).Time.IsZero() {
+				if r.Time.IsZero() {
+					t.Fatalf("got a result without time")
+				}
+				r.Time = time.Time{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMessageInfoType(t *testing.T) {
+	tests := []struct {
+		id, payload string
+	}{
+		{INFOTYPE_NODEINFO, `{"n1":{}}`},
+		{INFOTYPE_MESSAGE, "hello"},
+		{INFOTYPE_RESCHEDULE_OK, "default:a:b:n1:n2"},
+		{INFOTYPE_RESCHEDULE_FAIL, "default:a:b:n1:n2"},
+		{INFOTYPE_RESCHEDULE_STARTONERESCHEDULE, ""},
+		{INFOTYPE_RESCHEDULE_STOPONERESCHEDULE, ""},
+		{INFOTYPE_HEARTBEAT, ""},
+		{INFOTYPE_AUTH, AUTH_OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			msg, err := ParseMessage(tt.id, tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := msg.InfoType(); got != tt.id {
+				t.Fatalf("got type %q, want %q", got, tt.id)
+			}
+		})
+	}
+}
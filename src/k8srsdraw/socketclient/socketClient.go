@@ -1,9 +1,14 @@
 package socketclient
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"k8srsdraw/eventsource"
+	"k8srsdraw/logging"
 	"k8srsdraw/workqueue"
+	"log/slog"
 	"net"
 	"strings"
 	"time"
@@ -16,75 +21,88 @@ const (
 	INFOTYPE_RESCHEDULE_FAIL               string = "4"
 	INFOTYPE_RESCHEDULE_STARTONERESCHEDULE string = "5"
 	INFOTYPE_RESCHEDULE_STOPONERESCHEDULE  string = "6"
-)
-
-type PodInfos struct {
-	Name      string
-	Namespace string
-}
-
-type NodeInfos struct {
-	NodeName string
-	PodInfos []PodInfos
-}
+	// INFOTYPE_HEARTBEAT is sent by the server while it has nothing else to
+	// say, so that a silent connection can be told from a dead one.
+	INFOTYPE_HEARTBEAT string = "7"
 
-type Infos map[string]*NodeInfos
-
-type EventHandle interface {
-	Init(infos Infos)
-	AddNode(nodeName string)
-	DeleteNode(nodeName string)
-	AddPod(nodeName, podNamespace, podName string)
-	DeletePod(nodeName, podNamespace, podName string)
-	ReschedulePod(fromNodeName, toNodeName, podNamespace, fromPodName, toPodName string)
-	GetCurNodeInfos() Infos
-}
+	// INFOTYPE_AUTH is only used during the handshake: the client sends
+	// "0->Bearer <token>#" and the server answers "0->ok#" or hangs up.
+	INFOTYPE_AUTH string = "0"
+	AUTH_OK       string = "ok"
+)
 
 type SClientWorkItem struct {
 	workqueue.WorkItem
-	cmd      string
-	id       string
+	msg      Message
 	scClient *SClient
 }
 
-func NewSClientWorkItem(id, str string, c *SClient) *SClientWorkItem {
+func NewSClientWorkItem(msg Message, c *SClient) *SClientWorkItem {
 	return &SClientWorkItem{
-		cmd:      str,
+		msg:      msg,
 		scClient: c,
-		id:       id,
 	}
 }
 func (wi *SClientWorkItem) GetID() string {
-	return wi.id
+	return wi.msg.InfoType()
 }
 func (wi *SClientWorkItem) Run() {
-	wi.scClient.handleMessage(wi.id, wi.cmd)
+	wi.scClient.handleMessage(wi.msg)
+}
+
+// Options holds the optional connection settings of SClient.
+type Options struct {
+	// TLS enables TLS when not nil.
+	TLS *TLSOptions
+	// Token is sent as a bearer token right after connecting when not empty.
+	Token string
+	// ReadTimeout drops the connection when nothing, not even a heartbeat,
+	// arrived for that long. Zero waits forever.
+	ReadTimeout time.Duration
+	// WriteTimeout bounds every write to the server. Zero waits forever.
+	WriteTimeout time.Duration
+	// Logger defaults to logging.For("socketclient").
+	Logger *slog.Logger
 }
 
 type SClient struct {
 	host        string
 	port        string
+	opts        Options
 	conn        net.Conn
-	eventHandle EventHandle
+	eventHandle eventsource.EventHandle
 	isFirstRun  bool
 	workQueue   *workqueue.WorkQueue
+	logger      *slog.Logger
 	//infos       Infos
 }
 
-func NewSClient(host, port string, eventHandle EventHandle) *SClient {
+func NewSClient(host, port string, eventHandle eventsource.EventHandle, opts Options) *SClient {
+	logger := opts.Logger
+	if logger == nil {
+		logger = logging.For("socketclient")
+	}
 	return &SClient{
 		host:        host,
 		port:        port,
+		opts:        opts,
 		eventHandle: eventHandle,
 		isFirstRun:  true,
 		workQueue:   workqueue.NewWorQueue(),
+		logger:      logger,
 		//infos:       nil,
 	}
 }
-func getCommonPodInfos(nodeInfos1, nodeInfos2 []PodInfos) (node1Only, node2Only, common []PodInfos) {
-	node1Only = make([]PodInfos, 0)
-	node2Only = make([]PodInfos, 0)
-	common = make([]PodInfos, 0)
+
+// Close stops the work queue of sc once the messages received so far are
+// handled.
+func (sc *SClient) Close() {
+	sc.workQueue.Stop()
+}
+func getCommonPodInfos(nodeInfos1, nodeInfos2 []eventsource.PodInfos) (node1Only, node2Only, common []eventsource.PodInfos) {
+	node1Only = make([]eventsource.PodInfos, 0)
+	node2Only = make([]eventsource.PodInfos, 0)
+	common = make([]eventsource.PodInfos, 0)
 	for _, podInfo1 := range nodeInfos1 {
 		flag := false
 		for _, podInfo2 := range nodeInfos2 {
@@ -113,11 +131,8 @@ func getCommonPodInfos(nodeInfos1, nodeInfos2 []PodInfos) (node1Only, node2Only,
 	return
 }
 
-func (sc *SClient) CompareInfo(newInfos Infos) {
+func (sc *SClient) CompareInfo(newInfos eventsource.Infos) {
 	oldInfos := sc.eventHandle.GetCurNodeInfos()
-
-	//ret, _ := json.Marshal(oldInfos)
-	//fmt.Printf("CompareInfo:%s\n", ret)
 	for _, newInfo := range newInfos {
 		if oldInfo, ok := oldInfos[newInfo.NodeName]; !ok {
 			sc.eventHandle.AddNode(newInfo.NodeName)
@@ -140,82 +155,208 @@ func (sc *SClient) CompareInfo(newInfos Infos) {
 		}
 	}
 }
-func (sc *SClient) handleMessage(id, msg string) {
-	fmt.Printf("id=%s, msg:%s\n", id, msg)
-	switch id {
-	case INFOTYPE_NODEINFO:
-		infos := make(map[string]*NodeInfos)
-		//fmt.Printf("msg type INFOTYPE_NODEINFO %s\n", msg)
-		json.Unmarshal([]byte(msg), &infos)
+func (sc *SClient) handleMessage(msg Message) {
+	switch m := msg.(type) {
+	case *NodeInfoMessage:
 		if sc.isFirstRun {
 			sc.isFirstRun = false
-			sc.eventHandle.Init(infos)
+			sc.eventHandle.Init(m.Infos)
 		} else {
-			sc.CompareInfo(infos)
-		}
-	case INFOTYPE_RESCHEDULE_OK:
-		names := strings.Split(msg, ":")
-		podNs, fromPodName, toPodName, fromNode, toNode := names[0], names[1], names[2], names[3], names[4]
-		fmt.Printf("reschedule pod %s:%s from %s to %s %s Success %s\n", names[0], names[1], names[2], names[3], toPodName, names[4])
-		sc.eventHandle.ReschedulePod(fromNode, toNode, podNs, fromPodName, toPodName)
-
-		fmt.Printf("INFOTYPE_RESCHEDULE_OK stop %v\n", time.Now())
-		/*ret := sc.workQueue.RemoveItemByID(id)
-		if len(ret) > 0 {
-			sc.workQueue.AsyncRun(ret[len(ret)-1])
-		}*/
-	case INFOTYPE_RESCHEDULE_FAIL:
-		names := strings.Split(msg, ":")
-		fmt.Printf("reschedule pod %s:%s from %s to %s fail %s\n", names[0], names[1], names[2], names[3], names[4])
-	case INFOTYPE_MESSAGE:
-		//fmt.Printf("msg type INFOTYPE_MESSAGE:%s\n", msgStr)
-	}
-}
-func (sc *SClient) Run() {
-	con, err := net.Dial("tcp", sc.host+":"+sc.port)
+			sc.CompareInfo(m.Infos)
+		}
+	case *RescheduleResult:
+		sc.logger.Info("reschedule", "event", m.RescheduleEvent)
+		sc.eventHandle.ReschedulePod(m.RescheduleEvent)
+	case *RoundMarker:
+		if h, ok := sc.eventHandle.(eventsource.RoundHandle); ok {
+			if m.Start {
+				h.RoundStarted()
+			} else {
+				h.RoundFinished()
+			}
+		}
+	case *HeartbeatMessage:
+	case *TextMessage:
+		sc.logger.Debug("server message", "text", m.Text)
+	}
+}
+func (sc *SClient) dial() (net.Conn, error) {
+	addr := net.JoinHostPort(sc.host, sc.port)
+	if sc.opts.TLS == nil {
+		return net.Dial("tcp", addr)
+	}
+	cfg, err := sc.opts.TLS.Config()
+	if err != nil {
+		return nil, err
+	}
+	return tls.Dial("tcp", addr, cfg)
+}
+
+func (sc *SClient) write(con net.Conn, str string) error {
+	if sc.opts.WriteTimeout > 0 {
+		con.SetWriteDeadline(time.Now().Add(sc.opts.WriteTimeout))
+	}
+	_, err := con.Write([]byte(str))
+	return err
+}
+func (sc *SClient) read(con net.Conn) (string, error) {
+	if sc.opts.ReadTimeout > 0 {
+		con.SetReadDeadline(time.Now().Add(sc.opts.ReadTimeout))
+	}
+	return readBatch(con)
+}
+
+// ErrTokenWithoutTLS is returned when a token is configured without TLS,
+// the token would be sent in clear text.
+var ErrTokenWithoutTLS = errors.New("refusing to send the bearer token without TLS")
+
+// authenticate performs the bearer token handshake, it is a no-op when no
+// token is configured. It returns the frames the server sent right after
+// its reply.
+func (sc *SClient) authenticate(con net.Conn) ([]string, error) {
+	if sc.opts.Token == "" {
+		return nil, nil
+	}
+	if sc.opts.TLS == nil {
+		return nil, ErrTokenWithoutTLS
+	}
+	if err := sc.write(con, INFOTYPE_AUTH+"->Bearer "+sc.opts.Token+"#"); err != nil {
+		return nil, err
+	}
+	reply, err := sc.read(con)
+	if err != nil {
+		return nil, fmt.Errorf("no authentication reply: %v", err)
+	}
+	frames := SplitFrames(reply)
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("empty authentication reply")
+	}
+	msg, err := ParseFrame(frames[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid authentication reply: %v", err)
+	}
+	if r, ok := msg.(*AuthReply); !ok || r.Status != AUTH_OK {
+		return nil, fmt.Errorf("authentication rejected: %q", frames[0])
+	}
+	return frames[1:], nil
+}
+
+// readBatch reads until the server stops on a frame boundary ('#'),
+// whitespace after the last frame aside.
+func readBatch(con net.Conn) (string, error) {
+	strBuf := ""
+	bufBytes := make([]byte, 80960)
+	for {
+		length, err := con.Read(bufBytes)
+		if err != nil {
+			return "", err
+		}
+		strBuf += string(bufBytes[:length])
+		if strings.HasSuffix(strings.TrimRight(strBuf, " \r\n"), "#") {
+			return strBuf, nil
+		}
+	}
+}
+
+// dispatch queues the messages of frames, then acks them.
+func (sc *SClient) dispatch(con net.Conn, frames []string) error {
+	for _, str := range frames {
+		sc.logger.Debug("raw message", "payload", str)
+		msg, err := ParseFrame(str)
+		if err != nil {
+			sc.logger.Warn("drop message", "err", err)
+			continue
+		}
+		sc.workQueue.AsyncRun(NewSClientWorkItem(msg, sc))
+	}
+	return sc.write(con, "1")
+}
+
+// Run serves one connection until it breaks or ctx is done.
+func (sc *SClient) Run(ctx context.Context) {
+	con, err := sc.dial()
 	if err != nil {
+		sc.logger.Error("connect failed", "host", sc.host, "port", sc.port, "err", err)
 		return
 	}
 	defer con.Close()
+	stop := context.AfterFunc(ctx, func() {
+		con.Close()
+	})
+	defer stop()
 
+	pending, err := sc.authenticate(con)
 	if err != nil {
-		fmt.Println("Server not found.")
+		sc.logger.Error("authentication failed", "err", err)
 		return
 	}
-	fmt.Println("Connection OK.")
+	sc.logger.Info("connected", "host", sc.host, "port", sc.port)
+	status, _ := sc.eventHandle.(eventsource.ConnStatusHandle)
+	if status != nil {
+		status.Connected()
+	}
+	if len(pending) > 0 {
+		if err := sc.dispatch(con, pending); err != nil {
+			sc.logger.Warn("write to server failed", "err", err)
+			if status != nil {
+				status.Disconnected(err)
+			}
+			return
+		}
+	}
 
-	//msg := make([]byte, 80960)
 	for {
-		fmt.Printf("before read \n")
-		lastIndex := 0
-		strBuf := ""
-		for {
-			bufBytes := make([]byte, 80960)
-			length, err := con.Read(bufBytes)
-			if err != nil {
-				fmt.Printf("Error when read from server. err=%v\n", err)
-				return
-			}
-			strBuf += string(bufBytes[:length])
-			if bufBytes[length-1] == '#' {
-				length = lastIndex + length
-				break
-			} else {
-				lastIndex = lastIndex + length
+		strBuf, err := sc.read(con)
+		if err != nil {
+			sc.logger.Warn("read from server failed", "err", err)
+			if status != nil {
+				status.Disconnected(err)
 			}
+			return
+		}
+		if status != nil {
+			status.Alive(time.Now())
 		}
 
-		strs := strings.Split(strBuf, "#")
-		for _, str := range strs {
-			if str != "" {
-				//sc.handleMessage(str)
-				strs := strings.Split(str, "->")
-				if len(strs) == 2 {
-					sc.workQueue.AsyncRun(NewSClientWorkItem(strs[0], strs[1], sc))
-				}
+		if err := sc.dispatch(con, SplitFrames(strBuf)); err != nil {
+			sc.logger.Warn("write to server failed", "err", err)
+			if status != nil {
+				status.Disconnected(err)
 			}
+			return
 		}
-		con.Write([]byte("1"))
 	}
 
 }
+
+// Source is the EventSource reading from the rescheduler, it reconnects
+// whenever the connection is lost. All the connections share one client:
+// one work queue, and the snapshot sent on a reconnection is compared to
+// what eventHandle shows instead of being passed to Init again.
+type Source struct {
+	host string
+	port string
+	opts Options
+}
+
+func NewSource(host, port string, opts Options) *Source {
+	return &Source{
+		host: host,
+		port: port,
+		opts: opts,
+	}
+}
+
+func (s *Source) Run(ctx context.Context, eventHandle eventsource.EventHandle) error {
+	sc := NewSClient(s.host, s.port, eventHandle, s.opts)
+	defer sc.Close()
+	for {
+		sc.Run(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(3 * time.Second):
+		}
+		sc.logger.Info("reconnecting")
+	}
+}
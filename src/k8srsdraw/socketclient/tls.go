@@ -0,0 +1,49 @@
+package socketclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSOptions describes how to secure the connection to the rescheduler.
+type TLSOptions struct {
+	// CAFile verifies the server against this CA instead of the system pool.
+	CAFile string
+	// CertFile and KeyFile hold the client certificate, if the server
+	// asks for one.
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name checked in the server certificate,
+	// useful when dialing an ip address.
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// Config builds the tls.Config used to dial the server.
+func (o *TLSOptions) Config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+	if o.CAFile != "" {
+		caBytes, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("no certificate found in ca file %s", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
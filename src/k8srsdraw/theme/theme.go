@@ -0,0 +1,207 @@
+// Package theme holds the colors the X window draws with.
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Color is a color.RGBA written "#rrggbb" or "#rrggbbaa" in theme files.
+type Color color.RGBA
+
+func RGB(r, g, b uint8) Color {
+	return Color{r, g, b, 0xff}
+}
+
+func (c Color) RGBA() (r, g, b, a uint32) {
+	return color.RGBA(c).RGBA()
+}
+func (c Color) String() string {
+	if c.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+func (c Color) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	hex := strings.TrimPrefix(s, "#")
+	c.A = 0xff
+	var err error
+	switch len(hex) {
+	case 6:
+		_, err = fmt.Sscanf(hex, "%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 8:
+		_, err = fmt.Sscanf(hex, "%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	default:
+		err = fmt.Errorf("want #rrggbb or #rrggbbaa")
+	}
+	if err != nil {
+		return fmt.Errorf("bad color %q: %v", s, err)
+	}
+	return nil
+}
+
+type Theme struct {
+	Name       string `json:"name"`
+	Background Color  `json:"background"`
+	// Pod colors the pods when Namespaces is empty, and the plain text.
+	Pod    Color `json:"pod"`
+	Node   Color `json:"node"`
+	NodeOK Color `json:"nodeOK"`
+	// Line is the color of the reschedule arrows and of the headers.
+	Line   Color `json:"line"`
+	Stale  Color `json:"stale"`
+	Score  Color `json:"score"`
+	Better Color `json:"better"`
+	Worse  Color `json:"worse"`
+	// Namespaces is the palette the pods are colored from, every namespace
+	// always gets the same entry.
+	Namespaces []Color `json:"namespaces"`
+}
+
+// NamespaceColor returns the color of the pods of namespace ns.
+func (t *Theme) NamespaceColor(ns string) Color {
+	if len(t.Namespaces) == 0 {
+		return t.Pod
+	}
+	h := fnv.New32a()
+	h.Write([]byte(ns))
+	return t.Namespaces[h.Sum32()%uint32(len(t.Namespaces))]
+}
+
+var presets = map[string]Theme{
+	// dark is the historical look, with the namespaces colored
+	"dark": {
+		Background: RGB(0x00, 0x00, 0x00),
+		Pod:        RGB(0x00, 0xff, 0xff),
+		Node:       RGB(0xff, 0x00, 0x00),
+		NodeOK:     RGB(0x00, 0xff, 0x00),
+		Line:       RGB(0xff, 0xd7, 0x00),
+		Stale:      RGB(0xff, 0xa5, 0x00),
+		Score:      RGB(0xff, 0xff, 0xff),
+		Better:     RGB(0x00, 0xff, 0x00),
+		Worse:      RGB(0xff, 0x00, 0x00),
+		Namespaces: []Color{
+			RGB(0x00, 0xff, 0xff), RGB(0xff, 0x80, 0xff), RGB(0x80, 0x80, 0xff), RGB(0xff, 0xff, 0x80),
+			RGB(0x80, 0xff, 0xc0), RGB(0xff, 0xa0, 0x60), RGB(0xc0, 0xc0, 0xc0), RGB(0x40, 0xc0, 0xff),
+		},
+	},
+	"light": {
+		Background: RGB(0xff, 0xff, 0xff),
+		Pod:        RGB(0x00, 0x55, 0x99),
+		Node:       RGB(0xcc, 0x00, 0x00),
+		NodeOK:     RGB(0x00, 0x88, 0x00),
+		Line:       RGB(0xb0, 0x70, 0x00),
+		Stale:      RGB(0xcc, 0x66, 0x00),
+		Score:      RGB(0x20, 0x20, 0x20),
+		Better:     RGB(0x00, 0x88, 0x00),
+		Worse:      RGB(0xcc, 0x00, 0x00),
+		Namespaces: []Color{
+			RGB(0x00, 0x55, 0x99), RGB(0x99, 0x00, 0x77), RGB(0x44, 0x44, 0xcc), RGB(0x77, 0x55, 0x00),
+			RGB(0x00, 0x77, 0x55), RGB(0xaa, 0x44, 0x00), RGB(0x55, 0x55, 0x55), RGB(0x00, 0x77, 0xaa),
+		},
+	},
+	// high-contrast only uses fully saturated colors on black, pods are
+	// all white so that nothing depends on telling close hues apart
+	"high-contrast": {
+		Background: RGB(0x00, 0x00, 0x00),
+		Pod:        RGB(0xff, 0xff, 0xff),
+		Node:       RGB(0xff, 0x00, 0x00),
+		NodeOK:     RGB(0x00, 0xff, 0x00),
+		Line:       RGB(0xff, 0xff, 0x00),
+		Stale:      RGB(0xff, 0xff, 0x00),
+		Score:      RGB(0xff, 0xff, 0xff),
+		Better:     RGB(0x00, 0xff, 0x00),
+		Worse:      RGB(0xff, 0x00, 0x00),
+	},
+	// colorblind uses the Okabe-Ito palette, nodes are blue or vermillion
+	// instead of green or red
+	"colorblind": {
+		Background: RGB(0x00, 0x00, 0x00),
+		Pod:        RGB(0x56, 0xb4, 0xe9),
+		Node:       RGB(0xd5, 0x5e, 0x00),
+		NodeOK:     RGB(0x00, 0x72, 0xb2),
+		Line:       RGB(0xf0, 0xe4, 0x42),
+		Stale:      RGB(0xe6, 0x9f, 0x00),
+		Score:      RGB(0xff, 0xff, 0xff),
+		Better:     RGB(0x00, 0x72, 0xb2),
+		Worse:      RGB(0xd5, 0x5e, 0x00),
+		Namespaces: []Color{
+			RGB(0xe6, 0x9f, 0x00), RGB(0x56, 0xb4, 0xe9), RGB(0x00, 0x9e, 0x73),
+			RGB(0xf0, 0xe4, 0x42), RGB(0xcc, 0x79, 0xa7), RGB(0xff, 0xff, 0xff),
+		},
+	},
+}
+
+// Presets returns the names of the built-in themes, sorted.
+func Presets() []string {
+	ret := make([]string, 0, len(presets))
+	for name := range presets {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Preset returns a copy of the built-in theme name.
+func Preset(name string) (*Theme, bool) {
+	t, ok := presets[name]
+	if !ok {
+		return nil, false
+	}
+	t.Name = name
+	t.Namespaces = append([]Color(nil), t.Namespaces...)
+	return &t, true
+}
+
+// Default returns the dark preset.
+func Default() *Theme {
+	t, _ := Preset("dark")
+	return t
+}
+
+// Load reads a JSON theme file. The colors missing from the file are taken
+// from the preset named by its "base" field, dark by default.
+func Load(path string) (*Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var base struct {
+		Base string `json:"base"`
+	}
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if base.Base == "" {
+		base.Base = "dark"
+	}
+	t, ok := Preset(base.Base)
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown base theme %q", path, base.Base)
+	}
+	t.Name = path
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return t, nil
+}
+
+// Get returns the preset called spec, or else loads spec as a file.
+func Get(spec string) (*Theme, error) {
+	if t, ok := Preset(spec); ok {
+		return t, nil
+	}
+	return Load(spec)
+}
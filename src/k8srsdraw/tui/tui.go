@@ -0,0 +1,398 @@
+// Package tui draws the cluster in a terminal with ANSI escape codes, for
+// sessions without an X display.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"k8srsdraw/balance"
+	"k8srsdraw/eventsource"
+	"k8srsdraw/layout"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+const (
+	esc         = "\x1b["
+	styleReset  = esc + "0m"
+	styleRed    = esc + "31m"
+	styleGreen  = esc + "32m"
+	styleYellow = esc + "33m"
+	styleCyan   = esc + "36m"
+	styleBold   = esc + "1m"
+	styleRev    = esc + "7m"
+)
+
+const (
+	HIGHLIGHT_ADDED = iota
+	HIGHLIGHT_DELETED
+	HIGHLIGHT_MOVEDFROM
+	HIGHLIGHT_MOVEDTO
+)
+
+var (
+	HighlightDuration = 3 * time.Second
+	RefreshInterval   = 200 * time.Millisecond
+	// FooterLines is the room kept under the grid for the last reschedules
+	// and the connection status.
+	FooterLines = 5
+	// ScoreLines is the room kept for the balance score.
+	ScoreLines = 1
+	// DiffLines is the room kept for the comparison of the last round,
+	// once there is one.
+	DiffLines       = 4
+	DefaultWidth    = 120
+	DefaultHeight   = 40
+	MinBoxWidth     = 12
+	MinBoxHeight    = 3
+	groupMarkers    = map[int]string{HIGHLIGHT_ADDED: "+", HIGHLIGHT_DELETED: "-", HIGHLIGHT_MOVEDFROM: "↗", HIGHLIGHT_MOVEDTO: "↘"}
+	groupHighlights = map[int]string{HIGHLIGHT_ADDED: styleGreen + styleBold, HIGHLIGHT_DELETED: styleRed + styleBold,
+		HIGHLIGHT_MOVEDFROM: styleYellow + styleBold, HIGHLIGHT_MOVEDTO: styleYellow + styleRev}
+)
+
+type highlight struct {
+	kind  int
+	until time.Time
+}
+
+// TUI is an EventHandle rendering the node grid in a terminal. Call Run to
+// start drawing.
+type TUI struct {
+	mutex       sync.Mutex
+	out         *os.File
+	infos       eventsource.Infos
+	highlights  map[string]highlight
+	reschedules []eventsource.RescheduleEvent
+	staleAfter  time.Duration
+	lastSeen    time.Time
+	connected   bool
+	rounds      balance.RoundTracker
+}
+
+func NewTUI(out *os.File) *TUI {
+	return &TUI{
+		out:        out,
+		infos:      make(eventsource.Infos),
+		highlights: make(map[string]highlight),
+		staleAfter: 30 * time.Second,
+	}
+}
+
+func groupKey(nodeName, podNamespace string) string {
+	return nodeName + "/" + podNamespace
+}
+
+// SetStaleThreshold sets after how long without data the footer warns.
+func (t *TUI) SetStaleThreshold(d time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.staleAfter = d
+}
+
+func (t *TUI) highlight(nodeName, podNamespace string, kind int) {
+	t.highlights[groupKey(nodeName, podNamespace)] = highlight{kind: kind, until: time.Now().Add(HighlightDuration)}
+}
+
+func (t *TUI) Init(infos eventsource.Infos) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.infos = infos.Copy()
+}
+func (t *TUI) AddNode(nodeName string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.infos.AddNode(nodeName)
+}
+func (t *TUI) DeleteNode(nodeName string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.infos.DeleteNode(nodeName)
+}
+func (t *TUI) AddPod(nodeName, podNamespace, podName string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.infos.AddPod(nodeName, podNamespace, podName)
+	t.highlight(nodeName, podNamespace, HIGHLIGHT_ADDED)
+}
+func (t *TUI) DeletePod(nodeName, podNamespace, podName string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.infos.DeletePod(nodeName, podNamespace, podName)
+	t.highlight(nodeName, podNamespace, HIGHLIGHT_DELETED)
+}
+func (t *TUI) ReschedulePod(ev eventsource.RescheduleEvent) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.infos.ReschedulePod(ev)
+	if ev.Success {
+		t.highlight(ev.FromNodeName, ev.Namespace, HIGHLIGHT_MOVEDFROM)
+		t.highlight(ev.ToNodeName, ev.Namespace, HIGHLIGHT_MOVEDTO)
+	}
+	t.reschedules = append(t.reschedules, ev)
+	if len(t.reschedules) > FooterLines-1 {
+		t.reschedules = t.reschedules[len(t.reschedules)-(FooterLines-1):]
+	}
+}
+func (t *TUI) GetCurNodeInfos() eventsource.Infos {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.infos.Copy()
+}
+
+func (t *TUI) Connected() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.connected = true
+	if t.lastSeen.IsZero() {
+		t.lastSeen = time.Now()
+	}
+}
+func (t *TUI) Disconnected(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.connected = false
+}
+func (t *TUI) Alive(at time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.lastSeen = at
+}
+
+func (t *TUI) RoundStarted() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.rounds.RoundStarted(t.infos)
+}
+func (t *TUI) RoundFinished() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.rounds.RoundFinished(t.infos)
+}
+
+// Run takes over the terminal and redraws it until ctx is done.
+func (t *TUI) Run(ctx context.Context) {
+	// alternate screen, hidden cursor
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+	ticker := time.NewTicker(RefreshInterval)
+	defer ticker.Stop()
+	lastFrame := ""
+	for {
+		w, h, err := term.GetSize(int(t.out.Fd()))
+		if err != nil {
+			w, h = DefaultWidth, DefaultHeight
+		}
+		t.mutex.Lock()
+		frame := t.render(time.Now(), w, h)
+		t.mutex.Unlock()
+		if frame != lastFrame {
+			fmt.Fprint(t.out, frame)
+			lastFrame = frame
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// render builds a whole frame, the caller must hold t.mutex.
+func (t *TUI) render(now time.Time, w, h int) string {
+	var b strings.Builder
+	b.WriteString(esc + "H")
+	for key, hl := range t.highlights {
+		if now.After(hl.until) {
+			delete(t.highlights, key)
+		}
+	}
+
+	names := make([]string, 0, len(t.infos))
+	for name := range t.infos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	diff := t.rounds.Last()
+	footer := FooterLines + ScoreLines
+	if diff != nil {
+		footer += DiffLines
+	}
+	lines := 0
+	if len(names) == 0 {
+		b.WriteString("no nodes yet" + esc + "K\r\n")
+		lines++
+	} else {
+		rNum, cNum := layout.GetRowColum(len(names))
+		boxW := (w - (cNum - 1)) / cNum
+		if boxW < MinBoxWidth {
+			boxW = MinBoxWidth
+		}
+		boxH := (h - footer - (rNum - 1)) / rNum
+		if boxH < MinBoxHeight {
+			boxH = MinBoxHeight
+		}
+		grid := make([][][]string, rNum)
+		r, c := 0, 0
+		for _, name := range names {
+			grid[r] = append(grid[r], t.renderNode(t.infos[name], boxW, boxH, now))
+			r, c = layout.GetNextPos(rNum, cNum, r, c)
+		}
+		for i, row := range grid {
+			if i > 0 {
+				b.WriteString(esc + "K\r\n")
+				lines++
+			}
+			for l := 0; l < boxH; l++ {
+				for j, box := range row {
+					if j > 0 {
+						b.WriteString(" ")
+					}
+					b.WriteString(box[l])
+				}
+				b.WriteString(esc + "K\r\n")
+				lines++
+			}
+		}
+	}
+
+	score := balance.ComputeScore(t.infos, balance.NodeThresholds)
+	b.WriteString(truncate(score.String(), w) + esc + "K\r\n")
+	if diff != nil {
+		for _, line := range renderDiff(diff, w) {
+			b.WriteString(line + esc + "K\r\n")
+		}
+	}
+	for _, ev := range t.reschedules {
+		line := fmt.Sprintf("%s %s: %s ──▶ %s  (%s -> %s)", ev.Time.Format("15:04:05"), ev.Namespace,
+			ev.FromNodeName, ev.ToNodeName, ev.FromPodName, ev.ToPodName)
+		style := styleYellow
+		if !ev.Success {
+			line += " failed"
+			style = styleRed
+		}
+		if ev.Reason != "" {
+			line += ": " + ev.Reason
+		}
+		b.WriteString(style + truncate(line, w) + styleReset + esc + "K\r\n")
+	}
+	if status := t.statusStr(now); status != "" {
+		b.WriteString(styleRed + styleBold + truncate(status, w) + styleReset + esc + "K\r\n")
+	}
+	b.WriteString(esc + "J")
+	return b.String()
+}
+
+func (t *TUI) statusStr(now time.Time) string {
+	if t.lastSeen.IsZero() || t.staleAfter <= 0 || now.Sub(t.lastSeen) < t.staleAfter {
+		return ""
+	}
+	ret := "stale since " + t.lastSeen.Format("15:04:05")
+	if !t.connected {
+		ret += " (disconnected)"
+	}
+	return ret
+}
+
+// renderNode returns the boxH lines of a node box, each boxW cells wide.
+func (t *TUI) renderNode(nodeInfo *eventsource.NodeInfos, boxW, boxH int, now time.Time) []string {
+	groups := layout.GroupPods(nodeInfo.PodInfos)
+	border := styleRed
+	if balance.NodeThresholds.NodeOK(len(nodeInfo.PodInfos), len(groups)) {
+		border = styleGreen
+	}
+	inner := boxW - 2
+	title := truncate(fmt.Sprintf(" %s (%d) ", nodeInfo.NodeName, len(nodeInfo.PodInfos)), inner)
+	ret := make([]string, 0, boxH)
+	ret = append(ret, border+"┌"+styleBold+title+styleReset+border+strings.Repeat("─", inner-utf8.RuneCountInString(title))+"┐"+styleReset)
+
+	rows := boxH - 2
+	for i := 0; i < rows; i++ {
+		text, style := "", ""
+		if i == rows-1 && len(groups) > rows {
+			text = fmt.Sprintf(" … %d more", len(groups)-i)
+		} else if i < len(groups) {
+			g := groups[i]
+			marker := " "
+			if hl, ok := t.highlights[groupKey(nodeInfo.NodeName, g.Namespace)]; ok {
+				marker, style = groupMarkers[hl.kind], groupHighlights[hl.kind]
+			}
+			text = fmt.Sprintf("%s%s:%d [ %s ]", marker, g.Namespace, len(g.Names), strings.Join(g.Names, ", "))
+		}
+		text = pad(truncate(text, inner), inner)
+		if style == "" {
+			style = styleCyan
+		}
+		ret = append(ret, border+"│"+styleReset+style+text+styleReset+border+"│"+styleReset)
+	}
+	ret = append(ret, border+"└"+strings.Repeat("─", inner)+"┘"+styleReset)
+	return ret
+}
+
+// truncate cuts s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
+
+func pad(s string, n int) string {
+	if l := utf8.RuneCountInString(s); l < n {
+		return s + strings.Repeat(" ", n-l)
+	}
+	return s
+}
+
+// renderDiff compares the last round in DiffLines lines: the imbalance,
+// then pods and namespaces per node and nodes per namespace, as
+// before→after cells. Cells which changed are highlighted.
+func renderDiff(d *balance.RoundDiff, w int) []string {
+	verdict, style := "unchanged", styleCyan
+	if d.Improved() {
+		verdict, style = "better", styleGreen
+	} else if d.After.Imbalance > d.Before.Imbalance {
+		verdict, style = "worse", styleRed
+	}
+	head := fmt.Sprintf("round %s-%s  imbalance %d → %d ", d.Start.Format("15:04:05"), d.End.Format("15:04:05"),
+		d.Before.Imbalance, d.After.Imbalance)
+	ret := []string{styleBold + truncate(head, w) + styleReset + style + truncate(verdict, w-utf8.RuneCountInString(head)) + styleReset}
+	ret = append(ret, diffCells("pods/node ", d.Nodes(), d.Before.NodePods, d.After.NodePods, w))
+	ret = append(ret, diffCells("ns/node   ", d.Nodes(), d.Before.NodeNamespaces, d.After.NodeNamespaces, w))
+	ret = append(ret, diffCells("nodes/ns  ", d.Namespaces(), d.Before.NamespaceNodes, d.After.NamespaceNodes, w))
+	if len(ret) > DiffLines {
+		ret = ret[:DiffLines]
+	}
+	return ret
+}
+
+// diffCells writes "key before→after" cells after label as long as they fit
+// in w columns.
+func diffCells(label string, keys []string, before, after map[string]int, w int) string {
+	var b strings.Builder
+	b.WriteString(label)
+	width := utf8.RuneCountInString(label)
+	for _, k := range keys {
+		cell := fmt.Sprintf(" %s %d→%d", k, before[k], after[k])
+		cw := utf8.RuneCountInString(cell)
+		if width+cw > w {
+			break
+		}
+		width += cw
+		if before[k] != after[k] {
+			b.WriteString(styleYellow + styleBold + cell + styleReset)
+		} else {
+			b.WriteString(cell)
+		}
+	}
+	return b.String()
+}
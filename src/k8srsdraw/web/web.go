@@ -0,0 +1,195 @@
+// Package web shares the live view with a browser: an embedded page, the
+// current state as JSON and the events as a Server-Sent Events stream.
+package web
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"k8srsdraw/balance"
+	"k8srsdraw/eventsource"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// SubscriberBuffer is how many events a browser may lag behind before it is
+// dropped; it reconnects on its own and starts over from a snapshot.
+var SubscriberBuffer = 256
+
+// Server is an EventHandle which streams what it receives to browsers.
+//
+//	/            the embedded page
+//	/api/state   eventsource.Infos, as returned by GetCurNodeInfos
+//	/api/score   the balance.Score of the state and the node thresholds
+//	/events      one eventsource.Record per SSE message, starting with an
+//	             init record holding the whole state
+type Server struct {
+	mutex       sync.Mutex
+	addr        string
+	infos       eventsource.Infos
+	subscribers map[chan []byte]bool
+}
+
+func NewServer(addr string) *Server {
+	return &Server{
+		addr:        addr,
+		infos:       make(eventsource.Infos),
+		subscribers: make(map[chan []byte]bool),
+	}
+}
+
+// Handler serves the page and the api, for embedding in another server.
+func (s *Server) Handler() http.Handler {
+	static, _ := fs.Sub(staticFiles, "static")
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(static)))
+	mux.HandleFunc("/api/state", s.serveState)
+	mux.HandleFunc("/api/score", s.serveScore)
+	mux.HandleFunc("/events", s.serveEvents)
+	return mux
+}
+
+// Run serves http until ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{Addr: s.addr, Handler: s.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) serveState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.GetCurNodeInfos())
+}
+
+func (s *Server) serveScore(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Score      balance.Score      `json:"score"`
+		Thresholds balance.Thresholds `json:"thresholds"`
+	}{
+		Score:      balance.ComputeScore(s.GetCurNodeInfos(), balance.NodeThresholds),
+		Thresholds: balance.NodeThresholds,
+	})
+}
+
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := make(chan []byte, SubscriberBuffer)
+	s.mutex.Lock()
+	// the snapshot and the subscription are taken under the same lock so
+	// that no event falls in between
+	snapshot, _ := json.Marshal(&eventsource.Record{Time: time.Now(), Type: eventsource.RECORD_INIT, Infos: s.infos})
+	s.subscribers[ch] = true
+	s.mutex.Unlock()
+	defer s.unsubscribe(ch)
+
+	fmt.Fprintf(w, "data: %s\n\n", snapshot)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data, ok := <-ch:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) unsubscribe(ch chan []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.subscribers[ch] {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+}
+
+// broadcast sends r to every browser, the caller must hold s.mutex. A
+// browser which can not keep up is disconnected rather than waited for.
+func (s *Server) broadcast(r *eventsource.Record) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	for ch := range s.subscribers {
+		select {
+		case ch <- data:
+		default:
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (s *Server) Init(infos eventsource.Infos) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.infos = infos.Copy()
+	s.broadcast(&eventsource.Record{Type: eventsource.RECORD_INIT, Infos: s.infos})
+}
+func (s *Server) AddNode(nodeName string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.infos.AddNode(nodeName)
+	s.broadcast(&eventsource.Record{Type: eventsource.RECORD_ADDNODE, NodeName: nodeName})
+}
+func (s *Server) DeleteNode(nodeName string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.infos.DeleteNode(nodeName)
+	s.broadcast(&eventsource.Record{Type: eventsource.RECORD_DELETENODE, NodeName: nodeName})
+}
+func (s *Server) AddPod(nodeName, podNamespace, podName string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.infos.AddPod(nodeName, podNamespace, podName)
+	s.broadcast(&eventsource.Record{Type: eventsource.RECORD_ADDPOD,
+		NodeName: nodeName, Namespace: podNamespace, PodName: podName})
+}
+func (s *Server) DeletePod(nodeName, podNamespace, podName string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.infos.DeletePod(nodeName, podNamespace, podName)
+	s.broadcast(&eventsource.Record{Type: eventsource.RECORD_DELETEPOD,
+		NodeName: nodeName, Namespace: podNamespace, PodName: podName})
+}
+func (s *Server) ReschedulePod(ev eventsource.RescheduleEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.infos.ReschedulePod(ev)
+	s.broadcast(eventsource.NewRescheduleRecord(ev))
+}
+func (s *Server) GetCurNodeInfos() eventsource.Infos {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.infos.Copy()
+}
@@ -0,0 +1,103 @@
+package window
+
+import (
+	"fmt"
+	"image"
+	"sort"
+	"time"
+)
+
+// eventStr stamps an event shown in the tooltips with the time it
+// happened.
+func eventStr(format string, a ...interface{}) string {
+	return time.Now().Format("15:04:05") + " " + fmt.Sprintf(format, a...)
+}
+
+// setLastEvent records ev as the last event of n and of its pods of
+// namespace, if any are left.
+func (n *Node) setLastEvent(namespace, ev string) {
+	n.lastEvent = ev
+	if p := n.Pods[namespace]; p != nil {
+		p.lastEvent = ev
+	}
+}
+func (n *Node) tooltipLines() []string {
+	pl := n.GetPodList()
+	sort.Sort(pl)
+	podNum := 0
+	for _, p := range pl {
+		podNum += p.Count
+	}
+	lines := []string{n.Name, fmt.Sprintf("%d pods in %d namespaces", podNum, len(pl))}
+	for _, p := range pl {
+		lines = append(lines, fmt.Sprintf("- %s: %d", p.Namespace, p.Count))
+	}
+	if n.lastEvent != "" {
+		lines = append(lines, "last: "+n.lastEvent)
+	}
+	return lines
+}
+func (p *Pod) tooltipLines() []string {
+	lines := []string{fmt.Sprintf("%s: %d pods", p.Namespace, p.Count)}
+	for _, name := range p.Names.List(PodNameOrder) {
+		lines = append(lines, "- "+name)
+	}
+	if p.lastEvent != "" {
+		lines = append(lines, "last: "+p.lastEvent)
+	}
+	return lines
+}
+
+// hotspot is a box of the window with the tooltip shown over it.
+type hotspot struct {
+	box   image.Rectangle
+	lines []string
+}
+
+// hover moves the tooltip along with the pointer, in tells whether the
+// pointer is in the window at all. It runs on the X event loop and only
+// looks at the hotspots, never at the nodes.
+func (w *Window) hover(p image.Point, in bool) {
+	w.hoverMutex.Lock()
+	defer w.hoverMutex.Unlock()
+	w.pointer, w.hovering = p, in
+	w.showTooltip()
+}
+
+// drawTooltip takes the hotspots from the nodes as they are now and
+// updates the tooltip, what is under the pointer may have changed. The
+// caller must hold w.mutex.
+func (w *Window) drawTooltip() {
+	// pod groups come first, they lie on their nodes
+	spots := make([]hotspot, 0)
+	for _, n := range w.Nodes {
+		for _, p := range n.Pods {
+			spots = append(spots, hotspot{image.Rect(p.StartPoint.X, p.StartPoint.Y,
+				p.StartPoint.X+p.Width, p.StartPoint.Y+p.Height), p.tooltipLines()})
+		}
+	}
+	for _, n := range w.Nodes {
+		spots = append(spots, hotspot{image.Rect(n.StartPoint.X, n.StartPoint.Y,
+			n.StartPoint.X+n.Width, n.StartPoint.Y+n.Height), n.tooltipLines()})
+	}
+	w.hoverMutex.Lock()
+	defer w.hoverMutex.Unlock()
+	w.hotspots = spots
+	w.bounds = image.Rect(0, 0, w.width, w.height)
+	w.showTooltip()
+}
+
+// showTooltip shows the tooltip of the pod group or else the node under
+// the pointer, and hides it over anything else. The caller must hold
+// w.hoverMutex.
+func (w *Window) showTooltip() {
+	if w.hovering {
+		for _, spot := range w.hotspots {
+			if w.pointer.In(spot.box) {
+				w.tooltip.ShowAt(w.pointer, w.bounds, spot.lines)
+				return
+			}
+		}
+	}
+	w.tooltip.Hide()
+}
@@ -5,21 +5,26 @@ import (
 	"image"
 	"image/color"
 	"k8srsdraw/animation"
+	"k8srsdraw/balance"
 	"k8srsdraw/drawapi"
+	"k8srsdraw/eventsource"
+	"k8srsdraw/layout"
+	"k8srsdraw/logging"
+	"k8srsdraw/theme"
+	"log/slog"
 	"sort"
 	"sync"
 	"time"
 
+	"github.com/BurntSushi/xgb/xproto"
 	"github.com/BurntSushi/xgbutil"
+	"github.com/BurntSushi/xgbutil/keybind"
 	"github.com/BurntSushi/xgbutil/xevent"
 	"github.com/BurntSushi/xgbutil/xwindow"
 )
 
+// The colors come from the theme.Theme of the drawer.
 var (
-	PodColor        = color.RGBA{0x00, 0xff, 0xff, 0xff}
-	NodeColor       = color.RGBA{0xff, 0x00, 0x00, 0xff}
-	NodeOKColor     = color.RGBA{0x00, 0xff, 0x00, 0xff}
-	LineColor       = color.RGBA{255, 215, 0, 0xff}
 	NodeRowSpace    = 10
 	NodeColumSpace  = 10
 	NodeTopPadding  = 10
@@ -27,40 +32,37 @@ var (
 	PodPadding      = 10
 	PodHeight       = 22
 	TextLeftPadding = 5
+	StatusBarHeight = 20
+	ScoreBarHeight  = 20
+	// DiffPanelRatio is the share of the width given to the round
+	// comparison panel while it is shown.
+	DiffPanelRatio = 0.4
+	DiffRowHeight  = 18
+	DiffFontSize   = 13.0
+	// RescheduleDuration is how long a rescheduled pod travels between its
+	// nodes, along an arrow bent by ArrowBend.
+	RescheduleDuration = 2 * time.Second
+	ArrowBend          = 0.2
+	ArrowFadeDuration  = 600 * time.Millisecond
+	// TooltipFontSize and TooltipMaxWidth size the panel shown when the
+	// pointer rests on a node or a pod group.
+	TooltipFontSize = 13.0
+	TooltipMaxWidth = 360
+	// PodNameOrder is the order of the names in the labels of the pod
+	// groups, AbbreviatePodNames shortens the names of the pods of a
+	// ReplicaSet there.
+	PodNameOrder       = layout.OrderByName
+	AbbreviatePodNames = true
 )
 
-func getRowColum(num int) (r, c int) {
-	if num <= 4 {
-		return 1, num
-	} else if num <= 8 {
-		return 2, 4
-	} else if num <= 12 {
-		return 3, 4
-	} else if num <= 16 {
-		return 4, 4
-	} else if num <= 20 {
-		return 4, 5
-	} else if num <= 25 {
-		return 5, 5
-	} else if num <= 30 {
-		return 5, 6
-	} else {
-		if num%6 == 0 {
-			return 6, num / 6
-		} else {
-			return 6, num/6 + 1
-		}
-	}
-}
-func getNextPos(rNum, cNum int, curR, curC int) (r, c int) {
-	if curC+1 < cNum {
-		return curR, curC + 1
-	} else if curR+1 < rNum {
-		return curR + 1, 0
-	} else {
-		return rNum - 1, cNum - 1
-	}
-}
+// Layers of the scene, from the bottom up.
+const (
+	zNode = iota
+	zPod
+	zText
+	zArrow
+	zTooltip
+)
 
 type PodShowStatue struct {
 	ShowString     string
@@ -73,11 +75,12 @@ type Pod struct {
 	Width      int
 	Height     int
 	Namespace  string
-	Names      map[string]bool
+	Names      *layout.Names
 	Count      int
 	rect       *animation.Rect
 	text       *animation.TextWidgt
 	showStatus PodShowStatue
+	lastEvent  string
 }
 type PodList []*Pod
 
@@ -85,12 +88,7 @@ func (pl PodList) Len() int {
 	return len(pl)
 }
 func (pl PodList) Less(i, j int) bool {
-
-	if pl[i].Count != pl[j].Count {
-		return pl[i].Count > pl[j].Count
-	} else {
-		return pl[i].Namespace < pl[j].Namespace
-	}
+	return layout.GroupLess(pl[i].Count, pl[i].Namespace, pl[j].Count, pl[j].Namespace)
 }
 func (pl PodList) Swap(i, j int) {
 	pl[i], pl[j] = pl[j], pl[i]
@@ -101,7 +99,7 @@ func NewPod(startPoint drawapi.DrawPoint, w, h int, ns string, c int) *Pod {
 		Width:      w,
 		Height:     h,
 		Namespace:  ns,
-		Names:      make(map[string]bool),
+		Names:      layout.NewNames(),
 		Count:      c,
 		rect:       nil,
 		text:       nil,
@@ -130,22 +128,30 @@ func (p *Pod) IsShowStatusChanged() bool {
 	return false
 }
 func (p *Pod) GetShowStr() string {
+	names := p.Names.List(PodNameOrder)
+	if AbbreviatePodNames {
+		names = layout.Abbreviate(names)
+	}
 	var nameStr string = "["
-	for key, _ := range p.Names {
+	for _, name := range names {
 		if nameStr == "[" {
-			nameStr += " " + key
+			nameStr += " " + name
 		} else {
-			nameStr += ", " + key
+			nameStr += ", " + name
 		}
 	}
 	nameStr += " ]"
 	return fmt.Sprintf("%s:%d %s", p.Namespace, p.Count, nameStr)
 }
 func (p *Pod) Show(d *drawapi.Drawer) {
+	p.Hide()
 	showStr := p.GetShowStr()
-	p.rect = animation.NewRect(d, p.StartPoint, p.Width, p.Height, PodColor, false)
-	p.text = animation.NewTextWidgt(d, drawapi.DrawPoint{p.StartPoint.X + TextLeftPadding,
-		p.StartPoint.Y}, p.Width-TextLeftPadding, p.Height, showStr, 15, PodColor)
+	c := d.GetTheme().NamespaceColor(p.Namespace)
+	p.rect = animation.NewRect(d, p.StartPoint, p.Width, p.Height, c, false)
+	p.rect.Z = zPod
+	p.text = animation.NewTextWidgt(d, drawapi.DrawPoint{X: p.StartPoint.X + TextLeftPadding,
+		Y: p.StartPoint.Y}, p.Width-TextLeftPadding, p.Height, showStr, 15, c)
+	p.text.Z = zText
 	p.text.Draw()
 	p.rect.Draw()
 	p.showStatus.ShowString = showStr
@@ -174,6 +180,8 @@ type Node struct {
 	Width      int
 	Height     int
 	Pods       map[string]*Pod
+	shapes     []animation.DrawerShape
+	lastEvent  string
 }
 type NodeList []*Node
 
@@ -213,36 +221,51 @@ func (n *Node) GetPod(podNamespace string) *Pod {
 	p, _ := n.Pods[podNamespace]
 	return p
 }
-func (n *Node) AddPod(d *drawapi.Drawer, podNamespace, podName string) {
+
+// AddPod returns false when the pod was already on the node.
+func (n *Node) AddPod(d *drawapi.Drawer, podNamespace, podName string) bool {
+	return n.addPod(d, podNamespace, podName, false)
+}
+
+// AddMovedPod adds a pod rescheduled to the node, it returns false when
+// the pod was already on the node.
+func (n *Node) AddMovedPod(d *drawapi.Drawer, podNamespace, podName string) bool {
+	return n.addPod(d, podNamespace, podName, true)
+}
+func (n *Node) addPod(d *drawapi.Drawer, podNamespace, podName string, moved bool) bool {
+	add := func(names *layout.Names) bool {
+		if moved {
+			return names.AddMoved(podName)
+		}
+		return names.Add(podName)
+	}
 	p, find := n.Pods[podNamespace]
 	if find {
-		if _, ok := p.Names[podName]; ok {
-			fmt.Printf("pod:%s:%s is added return\n", podNamespace, podName)
-			return
+		if !add(p.Names) {
+			return false
 		}
-		p.Names[podName] = true
 		p.Count++
 		n.Draw(d)
 		p.Flicker(1 * time.Second)
 	} else {
 		sp, w, h := n.GetPodPos(len(n.Pods))
 		p = NewPod(sp, w, h, podNamespace, 1)
-		p.Names[podName] = true
+		add(p.Names)
 		n.Pods[podNamespace] = p
 		p.Show(d)
 		n.Draw(d)
 	}
-	//fmt.Printf("##### %s->%s: %d %d : %v\n", n.Name, p.Namespace, p.Count, len(p.Names), p.Names)
+	return true
 }
-func (n *Node) DeletePod(d *drawapi.Drawer, podNamespace, podName string) {
+
+// DeletePod returns false when the pod was not on the node.
+func (n *Node) DeletePod(d *drawapi.Drawer, podNamespace, podName string) bool {
 	p, find := n.Pods[podNamespace]
 	if find {
-		if _, ok := p.Names[podName]; !ok {
-			fmt.Printf("pod:%s:%s is not exist return\n", podNamespace, podName)
-			return
+		if !p.Names.Delete(podName) {
+			return false
 		}
 		p.Count--
-		delete(p.Names, podName)
 		if p.Count <= 0 {
 			p.Hide()
 			delete(n.Pods, podNamespace)
@@ -251,31 +274,37 @@ func (n *Node) DeletePod(d *drawapi.Drawer, podNamespace, podName string) {
 			n.Draw(d)
 			p.Flicker(1 * time.Second)
 		}
+		return true
 	}
+	return false
 }
 
 func (n *Node) Draw(d *drawapi.Drawer) {
+	d.Begin()
+	defer d.Commit()
 	nameHeight := 21
-	c := NodeColor
-	if len(n.Pods) <= 1 {
-		c = NodeOKColor
+	c := d.GetTheme().Node
+	podNum := 0
+	for _, p := range n.Pods {
+		podNum += p.Count
+	}
+	if balance.NodeThresholds.NodeOK(podNum, len(n.Pods)) {
+		c = d.GetTheme().NodeOK
 	}
+	n.Hide()
 	r1 := animation.NewRect(d, n.StartPoint, n.Width-20, nameHeight, c, false)
-	r2 := animation.NewRect(d, drawapi.DrawPoint{n.StartPoint.X,
-		n.StartPoint.Y + nameHeight}, n.Width, n.Height-nameHeight, c, false)
-	r1.Draw()
-	r2.Draw()
-	t := animation.NewTextWidgt(d, drawapi.DrawPoint{n.StartPoint.X + TextLeftPadding,
-		n.StartPoint.Y + 1}, n.Width-TextLeftPadding-21, nameHeight, n.Name, float64(nameHeight-4), c)
-	t.Hide()
-	t.Draw()
-	l := animation.NewLine(d, drawapi.DrawPoint{n.StartPoint.X, n.StartPoint.Y + 20},
-		drawapi.DrawPoint{n.StartPoint.X + n.Width, n.StartPoint.Y + 20}, c)
-	l.Draw()
-	for _, p := range n.Pods {
-		//if p.IsShowStatusChanged() {
-		p.Hide()
-		//}
+	r2 := animation.NewRect(d, drawapi.DrawPoint{X: n.StartPoint.X,
+		Y: n.StartPoint.Y + nameHeight}, n.Width, n.Height-nameHeight, c, false)
+	t := animation.NewTextWidgt(d, drawapi.DrawPoint{X: n.StartPoint.X + TextLeftPadding,
+		Y: n.StartPoint.Y + 1}, n.Width-TextLeftPadding-21, nameHeight, n.Name, float64(nameHeight-4), c)
+	// node names differ at their end
+	t.Layout.Ellipsis = drawapi.EllipsisMiddle
+	t.Z = zText
+	l := animation.NewLine(d, drawapi.DrawPoint{X: n.StartPoint.X, Y: n.StartPoint.Y + 20},
+		drawapi.DrawPoint{X: n.StartPoint.X + n.Width, Y: n.StartPoint.Y + 20}, c)
+	n.shapes = []animation.DrawerShape{r1, r2, t, l}
+	for _, s := range n.shapes {
+		s.Draw()
 	}
 
 	i := 0
@@ -290,30 +319,63 @@ func (n *Node) Draw(d *drawapi.Drawer) {
 	}
 }
 
+// Hide takes the frame of the node out of the scene, its pods stay.
+func (n *Node) Hide() {
+	for _, s := range n.shapes {
+		s.Hide()
+	}
+	n.shapes = nil
+}
+
 type Window struct {
 	width      int
 	height     int
-	background color.Color
+	theme      *theme.Theme
 	drawer     *drawapi.Drawer
 	xu         *xgbutil.XUtil
 	Nodes      map[string]*Node
-	canvas     *image.RGBA
 	xwin       *xwindow.Window
 	mutex      sync.Mutex
+	staleAfter time.Duration
+	lastSeen   time.Time
+	connected  bool
+	statusStr  string
+	statusText *animation.TextWidgt
+	logger     *slog.Logger
+	keys       map[string]func()
+	rounds     balance.RoundTracker
+	showDiff   bool
+	scoreStr   string
+	scoreText  *animation.TextWidgt
+	diffTexts  []*animation.TextWidgt
+	tooltip    *animation.Tooltip
+	// hoverMutex guards what the X event loop needs to move the tooltip,
+	// so that it never waits on mutex. pointer is where the pointer is in
+	// the window, hovering whether it is in at all, and hotspots what it
+	// may rest on, as of the last change of the nodes.
+	hoverMutex sync.Mutex
+	pointer    image.Point
+	hovering   bool
+	hotspots   []hotspot
+	bounds     image.Rectangle
 }
 
-func NewWindow(w, h int, bg color.Color) *Window {
+// NewWindow opens a w x h window drawn with the colors of th, the dark
+// preset if th is nil. It fails when the X server can not be reached.
+func NewWindow(w, h int, th *theme.Theme) (*Window, error) {
+	if th == nil {
+		th = theme.Default()
+	}
+	logger := logging.For("window")
 	xu, err := xgbutil.NewConn()
 	if err != nil {
-		fmt.Println(err)
-		return nil
+		return nil, fmt.Errorf("connect to X server: %v", err)
 	}
 
 	// just create a id for the window
 	xwin, err := xwindow.Generate(xu)
 	if err != nil {
-		fmt.Println(err)
-		return nil
+		return nil, fmt.Errorf("generate window id: %v", err)
 	}
 	// now, create the window
 	err = xwin.CreateChecked(
@@ -321,43 +383,180 @@ func NewWindow(w, h int, bg color.Color) *Window {
 		0, 0, w, h,   // window size
 		0) // related to event, not considered here
 	if err != nil {
-		fmt.Println(err)
-		return nil
+		return nil, fmt.Errorf("create window: %v", err)
 	}
 	// now we can see the window on the screen
 	xwin.Map()
+	keybind.Initialize(xu)
+	xwin.Listen(xproto.EventMaskKeyPress, xproto.EventMaskStructureNotify,
+		xproto.EventMaskPointerMotion, xproto.EventMaskLeaveWindow)
 
-	// 'rstr' calculates the data needed to draw
-	// 'painter' draw with the data on 'canvas'
-	canvas := image.NewRGBA(image.Rect(0, 0, w, h))
-
-	d := drawapi.NewDrawer(xu, xwin, canvas, color.RGBA{0x00, 0x00, 0x00, 0xff})
+	d := drawapi.NewDrawer(xu, xwin, image.NewRGBA(image.Rect(0, 0, w, h)), th)
 	d.Run()
-	return &Window{
+	win := &Window{
 		width:      w,
 		height:     h,
-		background: bg,
+		theme:      th,
 		drawer:     d,
 		xu:         xu,
 		Nodes:      make(map[string]*Node),
-		canvas:     canvas,
 		xwin:       xwin,
 		mutex:      sync.Mutex{},
-	}
+		staleAfter: 30 * time.Second,
+		logger:     logger,
+		keys:       make(map[string]func()),
+	}
+	win.tooltip = animation.NewTooltip(d, TooltipFontSize, TooltipMaxWidth, th.Pod, th.Background, th.Line)
+	win.tooltip.Z = zTooltip
+	win.keys["d"] = win.ToggleDiffView
+	xevent.KeyPressFun(func(xu *xgbutil.XUtil, ev xevent.KeyPressEvent) {
+		win.handleKey(keybind.LookupString(xu, ev.State, ev.Detail))
+	}).Connect(xu, xwin.Id)
+	xevent.ConfigureNotifyFun(func(xu *xgbutil.XUtil, ev xevent.ConfigureNotifyEvent) {
+		win.resize(int(ev.Width), int(ev.Height))
+	}).Connect(xu, xwin.Id)
+	xevent.MotionNotifyFun(func(xu *xgbutil.XUtil, ev xevent.MotionNotifyEvent) {
+		win.hover(image.Point{int(ev.EventX), int(ev.EventY)}, true)
+	}).Connect(xu, xwin.Id)
+	xevent.LeaveNotifyFun(func(xu *xgbutil.XUtil, ev xevent.LeaveNotifyEvent) {
+		win.hover(image.Point{}, false)
+	}).Connect(xu, xwin.Id)
+	go win.watchStale()
+	return win, nil
+}
+
+// SetLogger replaces the logger of the window, which defaults to
+// logging.For("window").
+func (w *Window) SetLogger(logger *slog.Logger) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.logger = logger
 }
 func (w *Window) GetDrawer() *drawapi.Drawer {
 	return w.drawer
 }
+
+// SetStaleThreshold sets how long the window may go without any data
+// before it warns that what it shows is stale.
+func (w *Window) SetStaleThreshold(d time.Duration) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.staleAfter = d
+}
+func (w *Window) SetLastSeen(t time.Time) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.lastSeen = t
+	w.drawStatus(false)
+}
+func (w *Window) SetConnected(connected bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.connected = connected
+	if connected && w.lastSeen.IsZero() {
+		w.lastSeen = time.Now()
+	}
+	w.drawStatus(false)
+}
+func (w *Window) getStatusStr(now time.Time) string {
+	if w.lastSeen.IsZero() {
+		return "waiting for the rescheduler"
+	}
+	if w.staleAfter <= 0 || now.Sub(w.lastSeen) < w.staleAfter {
+		return ""
+	}
+	ret := "stale since " + w.lastSeen.Format("15:04:05")
+	if !w.connected {
+		ret += " (disconnected)"
+	}
+	return ret
+}
+
+// drawStatus redraws the status bar if its text changed, the caller must
+// hold w.mutex.
+func (w *Window) drawStatus(force bool) {
+	str := w.getStatusStr(time.Now())
+	if str == w.statusStr && !force {
+		return
+	}
+	if w.statusText != nil {
+		w.statusText.Hide()
+	}
+	w.statusStr = str
+	w.statusText = animation.NewTextWidgt(w.drawer, drawapi.DrawPoint{X: NodeLeftPadding,
+		Y: w.height - StatusBarHeight}, w.width-NodeLeftPadding*2, StatusBarHeight-10, str, 14, w.theme.Stale)
+	w.statusText.Z = zText
+	w.statusText.Draw()
+}
+
+// drawScore redraws the score bar above the status bar if the score
+// changed.
+func (w *Window) drawScore(force bool) {
+	str := balance.ComputeScore(w.getInfos(), balance.NodeThresholds).String()
+	if str == w.scoreStr && !force {
+		return
+	}
+	if w.scoreText != nil {
+		w.scoreText.Hide()
+	}
+	w.scoreStr = str
+	w.scoreText = animation.NewTextWidgt(w.drawer, drawapi.DrawPoint{X: NodeLeftPadding,
+		Y: w.height - StatusBarHeight - ScoreBarHeight}, w.width-NodeLeftPadding*2, ScoreBarHeight-10, str, 14, w.theme.Score)
+	w.scoreText.Z = zText
+	w.scoreText.Draw()
+}
+func (w *Window) watchStale() {
+	for {
+		time.Sleep(1 * time.Second)
+		w.mutex.Lock()
+		w.drawStatus(false)
+		w.mutex.Unlock()
+	}
+}
+
+// WaitEvent runs the X event loop, key handlers are only called while it
+// runs.
 func (w *Window) WaitEvent() {
 	xevent.Main(w.xu)
 }
 
+// OnKey calls f, from the event loop, whenever key is pressed in the
+// window. key is named as by keybind.LookupString, e.g. "r".
+func (w *Window) OnKey(key string, f func()) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.keys[key] = f
+}
+
+// resize lays the window out again for its new size, in one frame.
+func (w *Window) resize(width, height int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if width == w.width && height == w.height {
+		return
+	}
+	w.width, w.height = width, height
+	w.drawer.Begin()
+	defer w.drawer.Commit()
+	w.drawer.Resize(width, height)
+	w.Update(true)
+}
+func (w *Window) handleKey(key string) {
+	w.mutex.Lock()
+	f := w.keys[key]
+	w.mutex.Unlock()
+	if f != nil {
+		f()
+	}
+}
+
 func (w *Window) AddNode(name string) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
 	_, find := w.Nodes[name]
 	if find == false {
-		w.Nodes[name] = NewNode(name, drawapi.DrawPoint{0, 0}, 0, 0)
+		w.Nodes[name] = NewNode(name, drawapi.DrawPoint{X: 0, Y: 0}, 0, 0)
+		w.Nodes[name].lastEvent = eventStr("node added")
 		w.Update(true)
 	}
 }
@@ -369,15 +568,33 @@ func (w *Window) GetNodeList() NodeList {
 	return nl
 }
 func (w *Window) AddPod(nodeName, podNamespace, podName string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	n, find := w.Nodes[nodeName]
 	if find == true {
-		n.AddPod(w.drawer, podNamespace, podName)
+		w.drawer.Begin()
+		defer w.drawer.Commit()
+		if !n.AddPod(w.drawer, podNamespace, podName) {
+			w.logger.Debug("pod already on node", "node", nodeName, "namespace", podNamespace, "pod", podName)
+		}
+		n.setLastEvent(podNamespace, eventStr("added %s", podName))
+		w.drawScore(false)
+		w.drawTooltip()
 	}
 }
 func (w *Window) DeletePod(nodeName, podNamespace, podName string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	n, find := w.Nodes[nodeName]
 	if find == true {
-		n.DeletePod(w.drawer, podNamespace, podName)
+		w.drawer.Begin()
+		defer w.drawer.Commit()
+		if !n.DeletePod(w.drawer, podNamespace, podName) {
+			w.logger.Debug("pod not on node", "node", nodeName, "namespace", podNamespace, "pod", podName)
+		}
+		n.setLastEvent(podNamespace, eventStr("deleted %s", podName))
+		w.drawScore(false)
+		w.drawTooltip()
 	}
 }
 
@@ -390,49 +607,95 @@ func (w *Window) ExistNode(name string) bool {
 func (w *Window) DeleteNode(name string) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
-	_, find := w.Nodes[name]
+	n, find := w.Nodes[name]
 	if find == true {
+		w.drawer.Begin()
+		defer w.drawer.Commit()
+		n.Hide()
+		for _, p := range n.Pods {
+			p.Hide()
+		}
 		delete(w.Nodes, name)
 		w.Update(true)
 	}
 }
 func (w *Window) MovePodFromTo(fromNode, toNode, podNamespace, fromPodName, toPodName string) {
-	//go func() {
+	arrow := w.startMove(fromNode, toNode, podNamespace, fromPodName)
+	if arrow == nil {
+		return
+	}
+	// the window is not locked while the token flies, so that the pointer
+	// and the other events are not held up by the animation
+	arrow.Fly(RescheduleDuration)
+	w.landMove(fromNode, toNode, podNamespace, toPodName)
+	arrow.FadeOut(ArrowFadeDuration)
+}
+
+// startMove takes the pod out of its group and draws the arrow to its
+// target slot, it returns nil if either node or the pod is gone.
+func (w *Window) startMove(fromNode, toNode, podNamespace, fromPodName string) *animation.CurvedArrow {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
 	nodeFrom, findFrom := w.Nodes[fromNode]
 	if findFrom == false {
-		return
+		return nil
 	}
 	nodeTo, findTo := w.Nodes[toNode]
 	if findTo == false {
-		return
+		return nil
 	}
 	pf := nodeFrom.GetPod(podNamespace)
 	if pf == nil {
-		return
+		return nil
 	}
 	pt := nodeTo.GetPod(podNamespace)
 	var ptPoint drawapi.DrawPoint
-
+	ptWidth, ptHeight := 0, 0
 	if pt != nil {
-		ptPoint = pt.StartPoint
+		ptPoint, ptWidth, ptHeight = pt.StartPoint, pt.Width, pt.Height
 	} else {
-		ptPoint, _, _ = nodeTo.GetPodPos(len(nodeTo.Pods))
+		ptPoint, ptWidth, ptHeight = nodeTo.GetPodPos(len(nodeTo.Pods))
 	}
 
-	startPoint := drawapi.DrawPoint{pf.StartPoint.X + pf.Width, pf.StartPoint.Y + pf.Height}
-	endPoint := ptPoint
+	// leave the source group and enter the target slot by their facing
+	// sides
+	startPoint := drawapi.PointF{X: float64(pf.StartPoint.X + pf.Width), Y: float64(pf.StartPoint.Y) + float64(pf.Height)/2}
+	endPoint := drawapi.PointF{X: float64(ptPoint.X), Y: float64(ptPoint.Y) + float64(ptHeight)/2}
+	if ptPoint.X+ptWidth/2 < pf.StartPoint.X+pf.Width/2 {
+		startPoint.X = float64(pf.StartPoint.X)
+		endPoint.X = float64(ptPoint.X + ptWidth)
+	}
 
-	w.GetDrawer().DrawLineWithAnimation(startPoint, endPoint, LineColor, 2*time.Second)
-	time.Sleep(300 * time.Millisecond)
-	w.GetDrawer().DrawLine(startPoint, endPoint, w.GetDrawer().GetBackGround())
-	nodeFrom.DeletePod(w.drawer, podNamespace, fromPodName)
-	nodeTo.AddPod(w.drawer, podNamespace, toPodName)
+	arrow := animation.NewCurvedArrow(w.drawer, startPoint, endPoint, ArrowBend, w.theme.Line,
+		w.theme.NamespaceColor(podNamespace))
+	arrow.Z = zArrow
+	// the pod leaves its group as the token sets off, and the target group
+	// counts it once the token landed
+	w.drawer.Batch(func() {
+		nodeFrom.DeletePod(w.drawer, podNamespace, fromPodName)
+		nodeFrom.setLastEvent(podNamespace, eventStr("moved %s to %s", fromPodName, toNode))
+		w.drawScore(false)
+		w.drawTooltip()
+		arrow.Draw()
+	})
+	return arrow
+}
 
-	//time.Sleep(3 * time.Second)
-	//w.Update(true)
-	//}()
+// landMove adds the pod to its target group, unless the node went away
+// while the token was flying.
+func (w *Window) landMove(fromNode, toNode, podNamespace, toPodName string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	nodeTo, findTo := w.Nodes[toNode]
+	if findTo == false {
+		return
+	}
+	w.drawer.Batch(func() {
+		nodeTo.AddMovedPod(w.drawer, podNamespace, toPodName)
+		nodeTo.setLastEvent(podNamespace, eventStr("moved %s from %s", toPodName, fromNode))
+		w.drawScore(false)
+		w.drawTooltip()
+	})
 }
 func (w *Window) MoveStatue(s int) {
 	if s == 1 {
@@ -441,31 +704,171 @@ func (w *Window) MoveStatue(s int) {
 		//	w.Update(false)
 	}
 }
+
+// Update lays the nodes out again, with force the bars and the diff panel
+// too. The shapes are replaced in place within one batch: the window never
+// shows a half laid out frame, and the shapes outside of the layout, like a
+// running reschedule arrow, are kept.
 func (w *Window) Update(force bool) {
+	w.drawer.Begin()
+	defer w.drawer.Commit()
+	// what is under the pointer may have moved
+	defer w.drawTooltip()
 	if force {
-		w.drawer.StopRun()
-		w.canvas = image.NewRGBA(image.Rect(0, 0, w.width, w.height))
-		w.drawer = drawapi.NewDrawer(w.xu, w.xwin, w.canvas, color.RGBA{0x00, 0x00, 0x00, 0xff})
-		w.drawer.Run()
+		w.drawStatus(true)
+		w.drawScore(true)
+		w.drawDiff()
 	}
 
 	if len(w.Nodes) == 0 {
 		return
 	}
-	rNum, cNum := getRowColum(len(w.Nodes))
-	nodeWidth := (w.width - NodeLeftPadding*2 - (cNum-1)*NodeColumSpace) / cNum
-	nodeHeight := (w.height - NodeTopPadding*2 - (rNum-1)*NodeRowSpace) / rNum
+	rNum, cNum := layout.GetRowColum(len(w.Nodes))
+	nodeWidth := (w.gridWidth() - NodeLeftPadding*2 - (cNum-1)*NodeColumSpace) / cNum
+	nodeHeight := (w.height - StatusBarHeight - ScoreBarHeight - NodeTopPadding*2 - (rNum-1)*NodeRowSpace) / rNum
 	r, c := 0, 0
 	nl := w.GetNodeList()
 	sort.Sort(nl)
 	for _, node := range nl {
-		node.StartPoint = drawapi.DrawPoint{NodeLeftPadding + c*(NodeColumSpace+nodeWidth),
-			NodeTopPadding + r*(NodeRowSpace+nodeHeight)}
+		node.StartPoint = drawapi.DrawPoint{X: NodeLeftPadding + c*(NodeColumSpace+nodeWidth),
+			Y: NodeTopPadding + r*(NodeRowSpace+nodeHeight)}
 		node.Width = nodeWidth
 		node.Height = nodeHeight
-		r, c = getNextPos(rNum, cNum, r, c)
+		r, c = layout.GetNextPos(rNum, cNum, r, c)
 	}
 	for _, node := range nl {
 		node.Draw(w.drawer)
 	}
 }
+
+// getInfos returns what the window shows, the caller must hold w.mutex.
+func (w *Window) getInfos() eventsource.Infos {
+	ret := make(eventsource.Infos)
+	for _, node := range w.Nodes {
+		t := &eventsource.NodeInfos{NodeName: node.Name,
+			PodInfos: make([]eventsource.PodInfos, 0),
+		}
+		ret[node.Name] = t
+		for _, p := range node.Pods {
+			for _, name := range p.Names.List(layout.OrderByName) {
+				t.PodInfos = append(t.PodInfos,
+					eventsource.PodInfos{Name: name, Namespace: p.Namespace})
+			}
+		}
+	}
+	return ret
+}
+func (w *Window) GetInfos() eventsource.Infos {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.getInfos()
+}
+
+func (w *Window) RoundStarted() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.rounds.RoundStarted(w.getInfos())
+}
+func (w *Window) RoundFinished() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.rounds.RoundFinished(w.getInfos()) != nil && w.showDiff {
+		w.drawDiff()
+	}
+}
+
+// ToggleDiffView shows or hides the comparison of the last round on the
+// right of the nodes, bound to the d key.
+func (w *Window) ToggleDiffView() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.showDiff = !w.showDiff
+	w.Update(true)
+}
+
+// gridWidth is the width left to the nodes.
+func (w *Window) gridWidth() int {
+	if !w.showDiff {
+		return w.width
+	}
+	return w.width - int(float64(w.width)*DiffPanelRatio)
+}
+
+// drawDiff draws the round comparison panel, the caller must hold w.mutex.
+// Every node gets a row with its pods and namespaces before and after the
+// round, colored the way the node itself is, then every namespace gets a
+// row with the number of nodes it is spread over.
+func (w *Window) drawDiff() {
+	w.drawer.Begin()
+	defer w.drawer.Commit()
+	for _, t := range w.diffTexts {
+		t.Hide()
+	}
+	w.diffTexts = nil
+	if !w.showDiff {
+		return
+	}
+	x := w.gridWidth()
+	width := w.width - x - NodeLeftPadding
+	bottom := w.height - StatusBarHeight - ScoreBarHeight
+	colW := width / 3
+	y := NodeTopPadding
+	cell := func(col int, str string, c color.Color) {
+		t := animation.NewTextWidgt(w.drawer, drawapi.DrawPoint{X: x + col*colW, Y: y},
+			colW-TextLeftPadding, DiffRowHeight, str, DiffFontSize, c)
+		t.Z = zText
+		t.Draw()
+		w.diffTexts = append(w.diffTexts, t)
+	}
+	row := func(c color.Color, cols ...string) bool {
+		if y+DiffRowHeight > bottom {
+			return false
+		}
+		for i, col := range cols {
+			cell(i, col, c)
+		}
+		y += DiffRowHeight
+		return true
+	}
+	nodeColor := func(podNum, nsNum int) color.Color {
+		if balance.NodeThresholds.NodeOK(podNum, nsNum) {
+			return w.theme.NodeOK
+		}
+		return w.theme.Node
+	}
+
+	d := w.rounds.Last()
+	if d == nil {
+		row(w.theme.Pod, "no round finished yet")
+		return
+	}
+	verdict, c := "unchanged", color.Color(w.theme.Pod)
+	if d.Improved() {
+		verdict, c = "better", w.theme.Better
+	} else if d.After.Imbalance > d.Before.Imbalance {
+		verdict, c = "worse", w.theme.Worse
+	}
+	row(c, "round "+d.End.Format("15:04:05"),
+		fmt.Sprintf("imbalance %d", d.Before.Imbalance),
+		fmt.Sprintf("%d, %s", d.After.Imbalance, verdict))
+	row(w.theme.Line, "node", "before", "after")
+	for _, n := range d.Nodes() {
+		if y+DiffRowHeight > bottom {
+			return
+		}
+		cell(0, n, w.theme.Pod)
+		for i, s := range []balance.Summary{d.Before, d.After} {
+			cell(i+1, fmt.Sprintf("%d pods, %d ns", s.NodePods[n], s.NodeNamespaces[n]),
+				nodeColor(s.NodePods[n], s.NodeNamespaces[n]))
+		}
+		y += DiffRowHeight
+	}
+	if !row(w.theme.Line, "namespace", "nodes before", "after") {
+		return
+	}
+	for _, ns := range d.Namespaces() {
+		if !row(w.theme.NamespaceColor(ns), ns, fmt.Sprint(d.Before.NamespaceNodes[ns]), fmt.Sprint(d.After.NamespaceNodes[ns])) {
+			return
+		}
+	}
+}
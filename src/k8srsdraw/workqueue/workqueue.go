@@ -1,6 +1,8 @@
 package workqueue
 
 import (
+	"k8srsdraw/logging"
+	"log/slog"
 	"sync"
 )
 
@@ -23,19 +25,36 @@ type WorkQueue struct {
 	workItemlist  []WorkItem
 	runStatuMutex sync.Mutex
 	runStatues    WorkQueuRunStatue
+	logger        *slog.Logger
+	stop          chan struct{}
+	stopOnce      sync.Once
+	done          chan struct{}
 }
 
 func NewWorQueue() *WorkQueue {
 	ret := &WorkQueue{
 		mutex:         sync.Mutex{},
 		runStatuMutex: sync.Mutex{},
-		wait:          make(chan int),
+		wait:          make(chan int, 1),
 		workItemlist:  make([]WorkItem, 0),
 		runStatues:    WORKQUEUE_NOSTARTED,
+		logger:        logging.For("workqueue"),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
 	}
 	ret.Start()
 	return ret
 }
+func (wq *WorkQueue) SetLogger(logger *slog.Logger) {
+	wq.mutex.Lock()
+	defer wq.mutex.Unlock()
+	wq.logger = logger
+}
+func (wq *WorkQueue) getLogger() *slog.Logger {
+	wq.mutex.Lock()
+	defer wq.mutex.Unlock()
+	return wq.logger
+}
 func (wq *WorkQueue) IsRunning() bool {
 	wq.runStatuMutex.Lock()
 	defer wq.runStatuMutex.Unlock()
@@ -67,6 +86,11 @@ func (wq *WorkQueue) PopWorkItem() WorkItem {
 		return ret
 	}
 }
+func (wq *WorkQueue) Len() int {
+	wq.mutex.Lock()
+	defer wq.mutex.Unlock()
+	return len(wq.workItemlist)
+}
 func (wq *WorkQueue) RemoveAllItem() {
 	wq.mutex.Lock()
 	defer wq.mutex.Unlock()
@@ -87,29 +111,51 @@ func (wq *WorkQueue) RemoveItemByID(id string) []WorkItem {
 	wq.workItemlist = tmp
 	return ret
 }
+
+// AsyncRun queues workItem and returns at once, it never waits for the
+// worker: one pending wake up is enough for the worker to drain the list.
 func (wq *WorkQueue) AsyncRun(workItem WorkItem) {
 	wq.AddWorkItem(workItem)
-	if wq.getRunStatues() == WORKQUEUE_IDLE {
-		wq.wait <- 1
+	select {
+	case wq.wait <- 1:
+	default:
 	}
 }
 func (wq *WorkQueue) Start() {
 	go func() {
+		defer close(wq.done)
 		for {
 			wq.setRunStatues(WORKQUEUE_IDLE)
 			select {
 			case <-wq.wait:
-				wq.setRunStatues(WORKQUEUE_RUNNING)
-				for {
-					workItem := wq.PopWorkItem()
-					if workItem != nil {
-						workItem.Run()
-					} else {
-						wq.setRunStatues(WORKQUEUE_IDLE)
-						break
-					}
-				}
+				wq.runAll()
+			case <-wq.stop:
+				wq.runAll()
+				wq.setRunStatues(WORKQUEUE_STOPED)
+				return
 			}
 		}
 	}()
 }
+
+// runAll runs the queued items until the list is empty.
+func (wq *WorkQueue) runAll() {
+	wq.setRunStatues(WORKQUEUE_RUNNING)
+	for {
+		workItem := wq.PopWorkItem()
+		if workItem == nil {
+			return
+		}
+		wq.getLogger().Debug("run work item", "id", workItem.GetID(), "pending", wq.Len())
+		workItem.Run()
+	}
+}
+
+// Stop runs the items queued so far, then stops the worker. It returns once
+// the worker is gone, items queued meanwhile may never run.
+func (wq *WorkQueue) Stop() {
+	wq.stopOnce.Do(func() {
+		close(wq.stop)
+	})
+	<-wq.done
+}